@@ -0,0 +1,157 @@
+package log4g
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	// CommonLogLayout is the text layout of the NCSA Common Log Format for the access log
+	CommonLogLayout = `%{remote_addr} - %{user} [%{time:02/Jan/2006:15:04:05 -0700}] "%{method} %{uri} %{proto}" %{status} %{bytes_clf}` + "\n"
+
+	// CombinedLogLayout is the text layout of the Apache Combined Log Format for the access log
+	CombinedLogLayout = `%{remote_addr} - %{user} [%{time:02/Jan/2006:15:04:05 -0700}] "%{method} %{uri} %{proto}" %{status} %{bytes_clf} "%{referer}" "%{user_agent}"` + "\n"
+)
+
+// accessLogWriter records the status and the bytes written of a response
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the first final status, the informational 1xx ones are followed by the final one
+func (w *accessLogWriter) WriteHeader(status int) {
+	if w.status == 0 && status >= http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer supports it
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ReadFrom implements io.ReaderFrom, the underlying writer may send the file by sendfile
+func (w *accessLogWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// hide ReadFrom of the wrapper to io.Copy
+		n, err = io.Copy(struct{ io.Writer }{w.ResponseWriter}, r)
+	}
+	w.bytes += int(n)
+	return
+}
+
+// Hijack implements http.Hijacker if the underlying writer supports it, the status is 101 if not written
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Push implements http.Pusher if the underlying writer supports it
+func (w *accessLogWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap return the underlying writer for http.ResponseController
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// clfBytes return the bytes in the Common Log Format, '-' if no bytes written
+func clfBytes(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return strconv.Itoa(n)
+}
+
+// clfString return the string in the Common Log Format, '-' if empty
+func clfString(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// AccessLog return a middleware which writes a event per request to the Logger with the level,
+// the message is like `"GET /a HTTP/1.1" 200 12`, and the request is written as the fields:
+// method, uri, path, proto, status, bytes, bytes_clf ('-' if no bytes), duration, remote_addr (without
+// the port), user, referer and user_agent ('-' if empty), along with the values carried by the request context.
+// Use CommonLogLayout or CombinedLogLayout as the layout of a text formatter to output the
+// NCSA formats, or use a json formatter to output all fields.
+func AccessLog(logger api.Logger, lvl api.Level) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			aw := &accessLogWriter{ResponseWriter: w}
+			next.ServeHTTP(aw, r)
+			if !logger.LevelEnabled(lvl) {
+				return
+			}
+			if aw.status == 0 {
+				aw.status = http.StatusOK
+			}
+
+			user := "-"
+			if u, _, ok := r.BasicAuth(); ok && u != "" {
+				user = u
+			}
+			host := r.RemoteAddr
+			if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				host = h
+			}
+			// the fields, trace, diagnostic context and markers carried by the request context are kept
+			ctx := api.WithFields(r.Context(),
+				api.Field{Key: "method", Value: r.Method},
+				api.Field{Key: "uri", Value: r.RequestURI},
+				api.Field{Key: "path", Value: r.URL.Path},
+				api.Field{Key: "proto", Value: r.Proto},
+				api.Field{Key: "status", Value: aw.status},
+				api.Field{Key: "bytes", Value: aw.bytes},
+				api.Field{Key: "bytes_clf", Value: clfBytes(aw.bytes)},
+				api.Field{Key: "duration", Value: time.Since(start)},
+				api.Field{Key: "remote_addr", Value: host},
+				api.Field{Key: "user", Value: user},
+				api.Field{Key: "referer", Value: clfString(r.Referer())},
+				api.Field{Key: "user_agent", Value: clfString(r.UserAgent())},
+			)
+			msg := fmt.Sprintf("%q %d %s", r.Method+" "+r.RequestURI+" "+r.Proto, aw.status, clfBytes(aw.bytes))
+			logger.WithCtx(ctx).Printf(lvl, "", msg)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package log4g
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestAccessLog(t *testing.T) {
+	ctx, op := newTestContext(t, CombinedLogLayout)
+	defer ctx.Close()
+
+	h := AccessLog(ctx.GetLogger("access"), api.Info)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/a?b=1", nil)
+	r.Header.Set("User-Agent", "test")
+	r.Header.Set("Referer", "http://x/")
+	r.SetBasicAuth("bob", "pwd")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Regexp(t, `^192\.0\.2\.1 - bob \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /a\?b=1 HTTP/1\.1" 201 5 "http://x/" "test"\n$`, op.String())
+}
+
+func TestAccessLogRequestContext(t *testing.T) {
+	ctx, op := newTestContext(t, "%{remote_addr}|%{trace_id}|%{mdc:user}|%{status}\n")
+	defer ctx.Close()
+
+	h := AccessLog(ctx.GetLogger("access"), api.Info)(http.NotFoundHandler())
+	r := httptest.NewRequest(http.MethodGet, "/a", nil)
+	r.RemoteAddr = "[2001:db8::1]:80"
+	rctx := api.WithTraceparent(r.Context(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r.WithContext(api.PutMDC(rctx, "user", "bob")))
+	r.RemoteAddr = "pipe"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, "2001:db8::1|4bf92f3577b34da6a3ce929d0e0e4736|bob|404\npipe|||404\n", op.String())
+}
+
+func TestAccessLogInformational(t *testing.T) {
+	ctx, op := newTestContext(t, CombinedLogLayout)
+	defer ctx.Close()
+
+	h := AccessLog(ctx.GetLogger("access"), api.Info)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusContinue)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/a", nil))
+
+	assert.Regexp(t, `"GET /a HTTP/1\.1" 202 - "-" "-"\n$`, op.String())
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	ctx, op := newTestContext(t, "%{msg}")
+	defer ctx.Close()
+	_ = ctx.Manager().SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"b1"}}},
+		Formats: []api.CfgFormat{{"type": "json", "name": "j1"}},
+		Outputs: []api.CfgOutput{{"type": "buf", "name": "b1", "format": "j1"}},
+	})
+
+	h := AccessLog(ctx.GetLogger("access"), api.Info)(http.NotFoundHandler())
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	assert.Regexp(t, `"msg":"\\"GET /x HTTP/1.1\\" 404 19","method":"GET","uri":"/x","path":"/x",`+
+		`"proto":"HTTP/1.1","status":404,"bytes":19,"bytes_clf":"19","duration":\d+,"remote_addr":"192.0.2.1","user":"-",`+
+		`"referer":"-","user_agent":"-"}`, op.String())
+}
+
+// hijackRecorder is a ResponseRecorder supporting http.Hijacker and io.ReaderFrom
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+	readFrom bool
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+func (r *hijackRecorder) ReadFrom(src io.Reader) (int64, error) {
+	r.readFrom = true
+	return io.Copy(r.ResponseRecorder, src)
+}
+
+func TestAccessLogWriterInterfaces(t *testing.T) {
+	ctx, op := newTestContext(t, CommonLogLayout)
+	defer ctx.Close()
+
+	var hijackErr, pushErr error
+	h := AccessLog(ctx.GetLogger("access"), api.Info)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _, hijackErr = w.(http.Hijacker).Hijack()
+		pushErr = w.(http.Pusher).Push("/a.css", nil)
+	}))
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.NoError(t, hijackErr)
+	assert.True(t, rec.hijacked)
+	assert.Equal(t, http.ErrNotSupported, pushErr)
+	assert.Regexp(t, `"GET /ws HTTP/1\.1" 101 -\n$`, op.String())
+
+	// hijacking is not supported by the plain recorder
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws", nil))
+	assert.Equal(t, http.ErrNotSupported, hijackErr)
+
+	h = AccessLog(ctx.GetLogger("access"), api.Info)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, io.LimitReader(strings.NewReader("hello"), 5))
+	}))
+	rec = &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/f", nil))
+	assert.True(t, rec.readFrom)
+	assert.Equal(t, "hello", rec.Body.String())
+	assert.Regexp(t, `"GET /f HTTP/1\.1" 200 5\n$`, op.String())
+}
@@ -0,0 +1,51 @@
+// Package adapter provides the adapters which let the logging interfaces expected by other libraries
+// write to log4g loggers, the interfaces are implemented by shape without depending on those libraries.
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/xtfly/log4g/api"
+)
+
+// VLevel return the level mapped from a verbosity level, 0 is Info, 1 is Debug and above is Trace
+func VLevel(v int) api.Level {
+	switch {
+	case v <= 0:
+		return api.Info
+	case v == 1:
+		return api.Debug
+	}
+	return api.Trace
+}
+
+// kvFields return the fields converted from key-value pairs, the key which is not a string
+// is formatted by fmt.Sprint, and the value of the odd key is nil.
+func kvFields(fields []api.Field, kvs []interface{}) []api.Field {
+	for i := 0; i < len(kvs); i += 2 {
+		k, ok := kvs[i].(string)
+		if !ok {
+			k = fmt.Sprint(kvs[i])
+		}
+		var v interface{}
+		if i+1 < len(kvs) {
+			v = kvs[i+1]
+		}
+		fields = append(fields, api.Field{Key: k, Value: v})
+	}
+	return fields
+}
+
+// callerCtx return a context carries the fields and the caller frame,
+// skip is the number of frames to skip above the caller of callerCtx.
+func callerCtx(fields []api.Field, skip int) context.Context {
+	ctx := api.WithFields(context.Background(), fields...)
+	pcs := make([]uintptr, 1)
+	if runtime.Callers(skip+3, pcs) == 1 {
+		frame, _ := runtime.CallersFrames(pcs).Next()
+		ctx = api.WithCaller(ctx, frame)
+	}
+	return ctx
+}
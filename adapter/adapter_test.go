@@ -0,0 +1,78 @@
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+	"github.com/xtfly/log4g/internal"
+)
+
+type bufOutput struct {
+	buf bytes.Buffer
+	f   api.Formatter
+}
+
+func (o *bufOutput) Send(e *api.Event)            { o.buf.Write(o.f.Format(e)) }
+func (o *bufOutput) SetFormatter(f api.Formatter) { o.f = f }
+func (o *bufOutput) CallerInfoFlag() int          { return o.f.CallerInfoFlag() }
+func (o *bufOutput) Close()                       {}
+
+func newTestLogger(t *testing.T, layout string) (api.Logger, *bufOutput) {
+	ctx, err := internal.NewLoggerContext(nil)
+	assert.NoError(t, err)
+	op := &bufOutput{}
+	ctx.Manager().RegisterOutputCreator("buf", func(api.CfgOutput) (api.Output, error) { return op, nil })
+	err = ctx.Manager().SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "debug", OutputNames: []string{"b1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": layout}},
+		Outputs: []api.CfgOutput{{"type": "buf", "name": "b1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	return ctx.GetLogger("adapter"), op
+}
+
+// logrLogger simulates logr.Logger which calls the sink with the call depth 1
+type logrLogger struct {
+	sink *LogrSink
+}
+
+func (l logrLogger) V(level int) logrLogger {
+	return l
+}
+
+func (l logrLogger) Info(msg string, kvs ...interface{}) {
+	l.sink.Info(0, msg, kvs...)
+}
+
+func TestLogrSink(t *testing.T) {
+	log, op := newTestLogger(t, "%{shortfunc}|%{lvl}|%{logger}|%{k}|%{error}|%{msg}\n")
+	sink := NewLogrSink(log)
+	sink.Init(RuntimeInfo{CallDepth: 1})
+
+	assert.True(t, sink.Enabled(1))
+	assert.False(t, sink.Enabled(2))
+
+	logrLogger{sink.WithName("a").WithName("b").WithValues("k", 1)}.Info("hello")
+	sink.WithCallDepth(-1).Error(errors.New("failed"), "world", "k", "v", "odd")
+	sink.Info(2, "trace")
+
+	assert.Equal(t, "TestLogrSink|INF|a.b|1|<nil>|hello\nTestLogrSink|ERR|<nil>|v|failed|world\n", op.buf.String())
+}
+
+func TestGrpcLogger(t *testing.T) {
+	log, op := newTestLogger(t, "%{shortfunc}|%{lvl}|%{msg}\n")
+	g := NewGrpcLogger(log)
+
+	assert.True(t, g.V(1))
+	assert.False(t, g.V(2))
+
+	g.Info("a", 1)
+	g.Warningln("b", 2)
+	g.Errorf("c%d", 3)
+	func() { g.InfoDepth(1, "d") }()
+
+	assert.Equal(t, "TestGrpcLogger|INF|a1\nTestGrpcLogger|WRN|b 2\nTestGrpcLogger|ERR|c3\nTestGrpcLogger|INF|d\n", op.buf.String())
+}
@@ -0,0 +1,112 @@
+package adapter
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/xtfly/log4g/api"
+	"github.com/xtfly/log4g/internal"
+)
+
+// GrpcLogger has the method set of grpclog.LoggerV2 and grpclog.DepthLoggerV2, it writes to a Logger,
+// so it can be passed to grpclog.SetLoggerV2 directly. The Fatal methods write with the Critical level,
+// flush the outputs and exit the process.
+type GrpcLogger struct {
+	logger api.Logger
+}
+
+// NewGrpcLogger return a GrpcLogger writes to the Logger
+func NewGrpcLogger(logger api.Logger) *GrpcLogger {
+	return &GrpcLogger{logger: logger}
+}
+
+// write the message, depth is the number of frames to skip above the caller of the GrpcLogger method
+func (g *GrpcLogger) write(lvl api.Level, depth int, msg string) {
+	if !g.logger.LevelEnabled(lvl) {
+		return
+	}
+	// skip GrpcLogger.write and the GrpcLogger method
+	ctx := callerCtx(nil, depth+1)
+	g.logger.WithCtx(ctx).Printf(lvl, "", msg)
+}
+
+func (g *GrpcLogger) fatal(depth int, msg string) {
+	g.write(api.Critical, depth+1, msg)
+	internal.Flush(g.logger)
+	os.Exit(1)
+}
+
+// Info logs to Info level, arguments are handled in the manner of fmt.Print
+func (g *GrpcLogger) Info(args ...interface{}) { g.write(api.Info, 0, fmt.Sprint(args...)) }
+
+// Infoln logs to Info level, arguments are handled in the manner of fmt.Println
+func (g *GrpcLogger) Infoln(args ...interface{}) { g.write(api.Info, 0, sprintln(args)) }
+
+// Infof logs to Info level, arguments are handled in the manner of fmt.Printf
+func (g *GrpcLogger) Infof(format string, args ...interface{}) {
+	g.write(api.Info, 0, fmt.Sprintf(format, args...))
+}
+
+// Warning logs to Warn level, arguments are handled in the manner of fmt.Print
+func (g *GrpcLogger) Warning(args ...interface{}) { g.write(api.Warn, 0, fmt.Sprint(args...)) }
+
+// Warningln logs to Warn level, arguments are handled in the manner of fmt.Println
+func (g *GrpcLogger) Warningln(args ...interface{}) { g.write(api.Warn, 0, sprintln(args)) }
+
+// Warningf logs to Warn level, arguments are handled in the manner of fmt.Printf
+func (g *GrpcLogger) Warningf(format string, args ...interface{}) {
+	g.write(api.Warn, 0, fmt.Sprintf(format, args...))
+}
+
+// Error logs to Error level, arguments are handled in the manner of fmt.Print
+func (g *GrpcLogger) Error(args ...interface{}) { g.write(api.Error, 0, fmt.Sprint(args...)) }
+
+// Errorln logs to Error level, arguments are handled in the manner of fmt.Println
+func (g *GrpcLogger) Errorln(args ...interface{}) { g.write(api.Error, 0, sprintln(args)) }
+
+// Errorf logs to Error level, arguments are handled in the manner of fmt.Printf
+func (g *GrpcLogger) Errorf(format string, args ...interface{}) {
+	g.write(api.Error, 0, fmt.Sprintf(format, args...))
+}
+
+// Fatal logs to Critical level and exit, arguments are handled in the manner of fmt.Print
+func (g *GrpcLogger) Fatal(args ...interface{}) { g.fatal(0, fmt.Sprint(args...)) }
+
+// Fatalln logs to Critical level and exit, arguments are handled in the manner of fmt.Println
+func (g *GrpcLogger) Fatalln(args ...interface{}) { g.fatal(0, sprintln(args)) }
+
+// Fatalf logs to Critical level and exit, arguments are handled in the manner of fmt.Printf
+func (g *GrpcLogger) Fatalf(format string, args ...interface{}) {
+	g.fatal(0, fmt.Sprintf(format, args...))
+}
+
+// V reports whether the Logger enables the level mapped from the verbosity level
+func (g *GrpcLogger) V(l int) bool {
+	return g.logger.LevelEnabled(VLevel(l))
+}
+
+// InfoDepth logs to Info level at the specified call depth
+func (g *GrpcLogger) InfoDepth(depth int, args ...interface{}) {
+	g.write(api.Info, depth, fmt.Sprint(args...))
+}
+
+// WarningDepth logs to Warn level at the specified call depth
+func (g *GrpcLogger) WarningDepth(depth int, args ...interface{}) {
+	g.write(api.Warn, depth, fmt.Sprint(args...))
+}
+
+// ErrorDepth logs to Error level at the specified call depth
+func (g *GrpcLogger) ErrorDepth(depth int, args ...interface{}) {
+	g.write(api.Error, depth, fmt.Sprint(args...))
+}
+
+// FatalDepth logs to Critical level at the specified call depth and exit
+func (g *GrpcLogger) FatalDepth(depth int, args ...interface{}) {
+	g.fatal(depth, fmt.Sprint(args...))
+}
+
+// sprintln format the arguments in the manner of fmt.Println without the trailing newline
+func sprintln(args []interface{}) string {
+	msg := fmt.Sprintln(args...)
+	return msg[:len(msg)-1]
+}
@@ -0,0 +1,94 @@
+package adapter
+
+import (
+	"github.com/xtfly/log4g/api"
+)
+
+// RuntimeInfo mirrors logr.RuntimeInfo
+type RuntimeInfo struct {
+	CallDepth int
+}
+
+// LogrSink has the method set of logr.LogSink and logr.CallDepthLogSink, it writes to a Logger,
+// the V-levels are mapped by VLevel and the key-value pairs are written as fields.
+// Because the methods of logr.LogSink use the types of logr, a thin wrapper is required
+// to pass it to logr.New without the dependency in log4g:
+//
+//	type sink struct{ *adapter.LogrSink }
+//
+//	func (s sink) Init(i logr.RuntimeInfo) { s.LogrSink.Init(adapter.RuntimeInfo{CallDepth: i.CallDepth}) }
+//	func (s sink) WithValues(kvs ...interface{}) logr.LogSink { return sink{s.LogrSink.WithValues(kvs...)} }
+//	func (s sink) WithName(name string) logr.LogSink { return sink{s.LogrSink.WithName(name)} }
+//	func (s sink) WithCallDepth(d int) logr.LogSink { return sink{s.LogrSink.WithCallDepth(d)} }
+//
+//	log := logr.New(sink{adapter.NewLogrSink(log4g.GetLogger("k8s"))})
+type LogrSink struct {
+	logger    api.Logger
+	fields    []api.Field
+	name      string
+	callDepth int
+}
+
+// NewLogrSink return a LogrSink writes to the Logger
+func NewLogrSink(logger api.Logger) *LogrSink {
+	return &LogrSink{logger: logger}
+}
+
+// Init receives the call depth of the logr.Logger
+func (s *LogrSink) Init(info RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+// Enabled reports whether the Logger enables the level mapped from the V-level
+func (s *LogrSink) Enabled(level int) bool {
+	return s.logger.LevelEnabled(VLevel(level))
+}
+
+// Info writes the message with the level mapped from the V-level
+func (s *LogrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write(VLevel(level), msg, nil, keysAndValues)
+}
+
+// Error writes the message with the Error level and the err as the 'error' field
+func (s *LogrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write(api.Error, msg, []api.Field{{Key: "error", Value: err}}, keysAndValues)
+}
+
+func (s *LogrSink) write(lvl api.Level, msg string, extra []api.Field, kvs []interface{}) {
+	if !s.logger.LevelEnabled(lvl) {
+		return
+	}
+	fields := make([]api.Field, len(s.fields), len(s.fields)+len(extra)+len(kvs)/2+2)
+	copy(fields, s.fields)
+	if s.name != "" {
+		fields = append(fields, api.Field{Key: "logger", Value: s.name})
+	}
+	fields = kvFields(append(fields, extra...), kvs)
+	// skip LogrSink.Info or LogrSink.Error
+	ctx := callerCtx(fields, s.callDepth+1)
+	s.logger.WithCtx(ctx).Printf(lvl, "", msg)
+}
+
+// WithValues return a new LogrSink with the key-value pairs accumulated
+func (s *LogrSink) WithValues(keysAndValues ...interface{}) *LogrSink {
+	n := *s
+	n.fields = kvFields(append([]api.Field(nil), s.fields...), keysAndValues)
+	return &n
+}
+
+// WithName return a new LogrSink with the name appended, the name is written as the 'logger' field
+func (s *LogrSink) WithName(name string) *LogrSink {
+	n := *s
+	if n.name != "" {
+		name = n.name + "." + name
+	}
+	n.name = name
+	return &n
+}
+
+// WithCallDepth return a new LogrSink which skips more frames to find the caller
+func (s *LogrSink) WithCallDepth(depth int) *LogrSink {
+	n := *s
+	n.callDepth += depth
+	return &n
+}
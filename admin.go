@@ -0,0 +1,122 @@
+package log4g
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+// LevelRequest is the body of PUT request to the admin handler
+type LevelRequest struct {
+	Name   string    `json:"name"`   // logger name or pattern, see Manager.SetLevel
+	Level  api.Level `json:"level"`  // empty means reset the level override
+	Expire string    `json:"expire"` // optional duration like '10m', revert the level after it
+}
+
+// levelExpiry records the override replaced by a expirable level change
+type levelExpiry struct {
+	timer   *time.Timer
+	prev    api.Level
+	hasPrev bool
+}
+
+type adminHandler struct {
+	sync.Mutex
+	manager api.Manager
+	expires map[string]*levelExpiry // key: logger name pattern
+}
+
+// NewAdminHandler return a http.Handler for inspecting and changing log levels at runtime:
+//
+//	GET  list all created loggers as JSON
+//	PUT  change the level of a logger by a JSON body of LevelRequest
+func NewAdminHandler(m api.Manager) http.Handler {
+	return &adminHandler{
+		manager: m,
+		expires: make(map[string]*levelExpiry),
+	}
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.manager.Loggers())
+	case http.MethodPut:
+		var req LevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.setLevel(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *adminHandler) setLevel(req *LevelRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("not set logger name")
+	}
+
+	var expire time.Duration
+	if req.Expire != "" {
+		d, err := time.ParseDuration(req.Expire)
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid expire %q", req.Expire)
+		}
+		expire = d
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	// the level to revert is the one before the first pending change
+	le, ok := h.expires[req.Name]
+	if ok {
+		le.timer.Stop()
+		delete(h.expires, req.Name)
+	} else {
+		le = &levelExpiry{}
+		le.prev, le.hasPrev = h.manager.LevelOverrides()[req.Name]
+	}
+
+	if req.Level == api.Uninitialized {
+		h.manager.ResetLevel(req.Name)
+	} else {
+		h.manager.SetLevel(req.Name, req.Level)
+	}
+
+	if expire > 0 {
+		le.timer = time.AfterFunc(expire, func() { h.revert(req.Name, le) })
+		h.expires[req.Name] = le
+	}
+	return nil
+}
+
+func (h *adminHandler) revert(name string, le *levelExpiry) {
+	h.Lock()
+	defer h.Unlock()
+	if h.expires[name] != le {
+		return
+	}
+	delete(h.expires, name)
+
+	if le.hasPrev {
+		h.manager.SetLevel(name, le.prev)
+	} else {
+		h.manager.ResetLevel(name)
+	}
+}
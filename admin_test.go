@@ -0,0 +1,67 @@
+package log4g
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestAdminHandler(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"m1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}"}},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	defer ctx.Close()
+	log := ctx.GetLogger("admin/a")
+	h := NewAdminHandler(ctx.Manager())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var infos []api.LoggerInfo
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &infos))
+	found := false
+	for _, info := range infos {
+		if info.Name == "admin/a" {
+			found = true
+			assert.Equal(t, api.Uninitialized, info.Level)
+			assert.Equal(t, log.Level(), info.EffectiveLevel)
+			assert.Equal(t, []string{"m1"}, info.Outputs)
+		}
+	}
+	assert.True(t, found)
+
+	w = httptest.NewRecorder()
+	body := `{"name": "admin", "level": "critical", "expire": "1h"}`
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, api.Critical, log.Level())
+
+	// expire the level change explicitly instead of waiting the timer
+	ah := h.(*adminHandler)
+	ah.Lock()
+	le := ah.expires["admin"]
+	ah.Unlock()
+	assert.NotNil(t, le)
+	assert.True(t, le.timer.Stop())
+	ah.revert("admin", le)
+	assert.Equal(t, api.All, log.Level())
+	assert.Empty(t, ctx.Manager().LevelOverrides())
+	assert.Empty(t, ah.expires)
+
+	w = httptest.NewRecorder()
+	body = `{"name": "admin", "level": "xx"}`
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
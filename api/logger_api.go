@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"time"
 )
 
@@ -64,6 +65,76 @@ type Writer interface {
 	Printf(lvl Level, fmt string, args ...interface{})
 }
 
+// MarkerWriter is the Writer which tags the events by markers, the writers of the loggers created by
+// the package implement it, Logger.WithCtx with a context carrying WithMarkers tags the events too.
+type MarkerWriter interface {
+	Writer
+
+	// WithMarker return a Writer which tags the events by the marker, see WithMarkers
+	WithMarker(name string) Writer
+}
+
+// LimitedWriter is the Writer which limits the times written by the call site, like in retry loops or
+// periodic checks. The writers of the loggers created by the package implement it, the state of
+// a call site is kept per logger, so the loggers and the logger contexts do not limit each other.
+type LimitedWriter interface {
+	Writer
+
+	// TraceOnce writes to log with level = Trace only at the first time of the call site
+	TraceOnce(msg ...interface{})
+
+	// TraceEveryN writes to log with level = Trace at the first and every n-th time of the call site
+	TraceEveryN(n int, msg ...interface{})
+
+	// TraceEvery writes to log with level = Trace at most once per d of the call site
+	TraceEvery(d time.Duration, msg ...interface{})
+
+	// DebugOnce writes to log with level = Debug only at the first time of the call site
+	DebugOnce(msg ...interface{})
+
+	// DebugEveryN writes to log with level = Debug at the first and every n-th time of the call site
+	DebugEveryN(n int, msg ...interface{})
+
+	// DebugEvery writes to log with level = Debug at most once per d of the call site
+	DebugEvery(d time.Duration, msg ...interface{})
+
+	// InfoOnce writes to log with level = Info only at the first time of the call site
+	InfoOnce(msg ...interface{})
+
+	// InfoEveryN writes to log with level = Info at the first and every n-th time of the call site
+	InfoEveryN(n int, msg ...interface{})
+
+	// InfoEvery writes to log with level = Info at most once per d of the call site
+	InfoEvery(d time.Duration, msg ...interface{})
+
+	// WarnOnce writes to log with level = Warn only at the first time of the call site
+	WarnOnce(msg ...interface{})
+
+	// WarnEveryN writes to log with level = Warn at the first and every n-th time of the call site
+	WarnEveryN(n int, msg ...interface{})
+
+	// WarnEvery writes to log with level = Warn at most once per d of the call site
+	WarnEvery(d time.Duration, msg ...interface{})
+
+	// ErrorOnce writes to log with level = Error only at the first time of the call site
+	ErrorOnce(msg ...interface{})
+
+	// ErrorEveryN writes to log with level = Error at the first and every n-th time of the call site
+	ErrorEveryN(n int, msg ...interface{})
+
+	// ErrorEvery writes to log with level = Error at most once per d of the call site
+	ErrorEvery(d time.Duration, msg ...interface{})
+
+	// CriticalOnce writes to log with level = Critical only at the first time of the call site
+	CriticalOnce(msg ...interface{})
+
+	// CriticalEveryN writes to log with level = Critical at the first and every n-th time of the call site
+	CriticalEveryN(n int, msg ...interface{})
+
+	// CriticalEvery writes to log with level = Critical at most once per d of the call site
+	CriticalEvery(d time.Duration, msg ...interface{})
+}
+
 // Field is logging message extend field
 type Field struct {
 	Key   string
@@ -104,6 +175,18 @@ type Factory interface {
 	GetLogger(name string) Logger
 }
 
+// LoggerContext is a self-contained logger hierarchy with its own manager, configuration and outputs,
+// multiple contexts can coexist in one process and be closed independently.
+type LoggerContext interface {
+	Factory
+
+	// Manager return the manager of the context
+	Manager() Manager
+
+	// Close all outputs of the context
+	Close()
+}
+
 // Event which is created when you logging and will send to Output,
 // The Output implementer uses the Formatter to format the event to a logging content.
 type Event struct {
@@ -129,6 +212,58 @@ func (e *Event) Message() string {
 	return msg
 }
 
+type fieldsKey struct{}
+
+// WithFields return a copy of ctx which carries the fields appended to the ones carried by ctx,
+// each field is also carried as a value keyed by its key, so the %{key} verb can output it.
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	prev := FieldsFrom(ctx)
+	all := make([]Field, 0, len(prev)+len(fields))
+	all = append(append(all, prev...), fields...)
+	for i := range fields {
+		ctx = context.WithValue(ctx, fields[i].Key, fields[i].Value)
+	}
+	return context.WithValue(ctx, fieldsKey{}, all)
+}
+
+// FieldsFrom return the fields carried by ctx in the order of appending
+func FieldsFrom(ctx context.Context) []Field {
+	fields, _ := ctx.Value(fieldsKey{}).([]Field)
+	return fields
+}
+
+type traceparentKey struct{}
+
+// WithTraceparent return a copy of ctx which carries the W3C traceparent header value,
+// like '00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// TraceparentFrom return the W3C traceparent header value carried by ctx
+func TraceparentFrom(ctx context.Context) string {
+	tp, _ := ctx.Value(traceparentKey{}).(string)
+	return tp
+}
+
+type callerKey struct{}
+
+// WithCaller return a copy of ctx which carries the caller frame, the Writer created by
+// Logger.WithCtx reports the caller info by the frame instead of the call depth,
+// it is used by the adapters which write logs on behalf of other call sites.
+func WithCaller(ctx context.Context, frame runtime.Frame) context.Context {
+	return context.WithValue(ctx, callerKey{}, frame)
+}
+
+// CallerFrom return the caller frame carried by ctx
+func CallerFrom(ctx context.Context) (frame runtime.Frame, ok bool) {
+	frame, ok = ctx.Value(callerKey{}).(runtime.Frame)
+	return
+}
+
 // -----------------------------
 // ------Formatter API----------
 // -----------------------------
@@ -163,3 +298,40 @@ type Output interface {
 	// Close the output and quit the loop routine
 	Close()
 }
+
+// FlushOutput is the Output which buffers events and can be flushed
+type FlushOutput interface {
+	Output
+
+	// Flush write all buffered events to the target
+	Flush()
+}
+
+// OutputStats is the runtime statistics of a Output
+type OutputStats struct {
+	Events  uint64 `json:"events"`  // the number of events written
+	Bytes   uint64 `json:"bytes"`   // the number of bytes written
+	Dropped uint64 `json:"dropped"` // the number of events dropped by the threshold
+	Errors  uint64 `json:"errors"`  // the number of events failed to write
+
+	// Targets is the number of events reached each target, only reported by the failover output
+	Targets map[string]uint64 `json:"targets,omitempty"`
+}
+
+// ErrorOutput is the Output which reports the error of writing a event
+type ErrorOutput interface {
+	Output
+
+	// Write the event like Send and return the error of writing, the event dropped by the threshold
+	// or filters is not a error, the output writes asynchronously always returns nil and reports
+	// the error to the error handler of the manager.
+	Write(e *Event) error
+}
+
+// StatsOutput is the Output which reports its runtime statistics
+type StatsOutput interface {
+	Output
+
+	// Stats return a snapshot of the runtime statistics
+	Stats() OutputStats
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithFields(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, FieldsFrom(ctx))
+
+	ctx1 := WithFields(ctx, Field{Key: "a", Value: 1})
+	ctx2 := WithFields(ctx1, Field{Key: "b", Value: 2}, Field{Key: "a", Value: 3})
+	assert.Equal(t, []Field{{"a", 1}}, FieldsFrom(ctx1))
+	assert.Equal(t, []Field{{"a", 1}, {"b", 2}, {"a", 3}}, FieldsFrom(ctx2))
+	assert.Equal(t, 3, ctx2.Value("a"))
+	assert.Equal(t, ctx2, WithFields(ctx2))
+}
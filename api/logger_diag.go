@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// DiagContext is the diagnostic context like the MDC (mapped) and NDC (nested) of log4j,
+// it is immutable and carried by context.Context, so it is independent of goroutines.
+type DiagContext struct {
+	stack  []string
+	values map[string]string
+	keys   []string // sorted keys of values
+}
+
+type diagKey struct{}
+
+// DiagContextFrom return the diagnostic context carried by ctx, nil if absent
+func DiagContextFrom(ctx context.Context) *DiagContext {
+	dc, _ := ctx.Value(diagKey{}).(*DiagContext)
+	return dc
+}
+
+// PushNDC return a copy of ctx which carries the nested diagnostic context with msg pushed
+func PushNDC(ctx context.Context, msg string) context.Context {
+	dc := DiagContextFrom(ctx).clone()
+	dc.stack = append(dc.stack, msg)
+	return context.WithValue(ctx, diagKey{}, dc)
+}
+
+// PopNDC return a copy of ctx which carries the nested diagnostic context with the last message popped
+func PopNDC(ctx context.Context) context.Context {
+	old := DiagContextFrom(ctx)
+	if len(old.NDC()) == 0 {
+		return ctx
+	}
+	dc := old.clone()
+	dc.stack = dc.stack[:len(dc.stack)-1]
+	return context.WithValue(ctx, diagKey{}, dc)
+}
+
+// PutMDC return a copy of ctx which carries the mapped diagnostic context with the key set to value
+func PutMDC(ctx context.Context, key, value string) context.Context {
+	dc := DiagContextFrom(ctx).clone()
+	if _, ok := dc.values[key]; !ok {
+		dc.keys = append(dc.keys, key)
+		sort.Strings(dc.keys)
+	}
+	dc.values[key] = value
+	return context.WithValue(ctx, diagKey{}, dc)
+}
+
+// RemoveMDC return a copy of ctx which carries the mapped diagnostic context with the key removed
+func RemoveMDC(ctx context.Context, key string) context.Context {
+	old := DiagContextFrom(ctx)
+	if _, ok := old.MDC(key); !ok {
+		return ctx
+	}
+	dc := old.clone()
+	delete(dc.values, key)
+	keys := dc.keys[:0]
+	for _, k := range dc.keys {
+		if k != key {
+			keys = append(keys, k)
+		}
+	}
+	dc.keys = keys
+	return context.WithValue(ctx, diagKey{}, dc)
+}
+
+// NDC return the messages of the nested diagnostic context from the outermost
+func (dc *DiagContext) NDC() []string {
+	if dc == nil {
+		return nil
+	}
+	return dc.stack
+}
+
+// MDC return the value of the key in the mapped diagnostic context
+func (dc *DiagContext) MDC(key string) (value string, ok bool) {
+	if dc == nil {
+		return "", false
+	}
+	value, ok = dc.values[key]
+	return
+}
+
+// MDCKeys return the sorted keys of the mapped diagnostic context
+func (dc *DiagContext) MDCKeys() []string {
+	if dc == nil {
+		return nil
+	}
+	return dc.keys
+}
+
+// String return the mapped diagnostic context as 'k1=v1,k2=v2' sorted by the keys
+func (dc *DiagContext) String() string {
+	var sb strings.Builder
+	for i, k := range dc.MDCKeys() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(dc.values[k])
+	}
+	return sb.String()
+}
+
+// clone return a deep copy of dc, a empty one if dc is nil
+func (dc *DiagContext) clone() *DiagContext {
+	n := &DiagContext{values: make(map[string]string)}
+	if dc == nil {
+		return n
+	}
+	n.stack = append([]string(nil), dc.stack...)
+	n.keys = append([]string(nil), dc.keys...)
+	for k, v := range dc.values {
+		n.values[k] = v
+	}
+	return n
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Nil(t, DiagContextFrom(ctx))
+	assert.Equal(t, ctx, PopNDC(ctx))
+	assert.Equal(t, ctx, RemoveMDC(ctx, "a"))
+
+	ctx1 := PushNDC(PutMDC(ctx, "b", "2"), "outer")
+	ctx2 := PushNDC(PutMDC(ctx1, "a", "1"), "inner")
+	dc1, dc2 := DiagContextFrom(ctx1), DiagContextFrom(ctx2)
+	assert.Equal(t, []string{"outer"}, dc1.NDC())
+	assert.Equal(t, []string{"outer", "inner"}, dc2.NDC())
+	assert.Equal(t, "b=2", dc1.String())
+	assert.Equal(t, "a=1,b=2", dc2.String())
+	v, ok := dc2.MDC("a")
+	assert.True(t, ok)
+	assert.Equal(t, "1", v)
+
+	ctx3 := RemoveMDC(PopNDC(ctx2), "b")
+	dc3 := DiagContextFrom(ctx3)
+	assert.Equal(t, []string{"outer"}, dc3.NDC())
+	assert.Equal(t, []string{"a"}, dc3.MDCKeys())
+	_, ok = dc3.MDC("b")
+	assert.False(t, ok)
+	// the parent contexts are not changed
+	assert.Equal(t, "a=1,b=2", dc2.String())
+	assert.Equal(t, []string{"outer", "inner"}, dc2.NDC())
+}
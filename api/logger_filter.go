@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FilterResult is the decision of a Filter on a event
+type FilterResult int
+
+// Filter results
+const (
+	Neutral FilterResult = iota // pass the event to the next filter
+	Accept                      // accept the event without the following filters
+	Deny                        // drop the event
+)
+
+var filterResultStrings = map[FilterResult]string{
+	Neutral: "NEUTRAL",
+	Accept:  "ACCEPT",
+	Deny:    "DENY",
+}
+
+// String returns the text for the filter result.
+func (r FilterResult) String() string {
+	return filterResultStrings[r]
+}
+
+// FilterResultFrom returns the filter result from the case insensitive string
+func FilterResultFrom(str string) (FilterResult, error) {
+	for k, v := range filterResultStrings {
+		if strings.ToUpper(str) == v {
+			return k, nil
+		}
+	}
+	return Neutral, fmt.Errorf("invalid filter result %q", str)
+}
+
+// Filter decides whether a event is written, the filters attached to a logger or a output
+// are evaluated in order until one returns Accept or Deny, the event is written if none denies it.
+type Filter interface {
+	Filter(e *Event) FilterResult
+}
+
+// FilterOutput is the Output which filters events by the filters attached to it
+type FilterOutput interface {
+	Output
+
+	// SetFilters set the filters evaluated after the threshold level
+	SetFilters(fs []Filter)
+}
+
+// FilterFuncCreator is function will to create a Filter instance by configuration
+type FilterFuncCreator func(cfg CfgFilter) (Filter, error)
+
+// CfgFilter represents the configuration of a filter
+type CfgFilter map[string]string
+
+// Name return the name of Filter
+func (c CfgFilter) Name() string {
+	return c["name"]
+}
+
+// Type return the type of Filter
+func (c CfgFilter) Type() string {
+	return c["type"]
+}
+
+// Clone return a copy of the configuration
+func (c CfgFilter) Clone() CfgFilter {
+	n := make(CfgFilter, len(c))
+	for k, v := range c {
+		n[k] = v
+	}
+	return n
+}
@@ -1,6 +1,9 @@
 package api
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // Level type for a logger
 type Level int
@@ -59,3 +62,18 @@ func LevelFrom(str string) Level {
 	}
 	return Uninitialized
 }
+
+// MarshalText implements encoding.TextMarshaler, the level is encoded as its text
+func (lvl Level) MarshalText() ([]byte, error) {
+	return []byte(lvl.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, the text is case insensitive
+func (lvl *Level) UnmarshalText(text []byte) error {
+	l := LevelFrom(string(text))
+	if l == Uninitialized && len(text) != 0 {
+		return fmt.Errorf("invalid level %q", text)
+	}
+	*lvl = l
+	return nil
+}
@@ -15,3 +15,16 @@ func TestLevel(t *testing.T) {
 	assert.Equal(t, "ERROR", Error.String())
 	assert.Equal(t, "ERR", Error.ShortStr())
 }
+
+func TestLevelText(t *testing.T) {
+	bs, err := Warn.MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "WARN", string(bs))
+
+	var lvl Level
+	assert.NoError(t, lvl.UnmarshalText([]byte("debug")))
+	assert.Equal(t, Debug, lvl)
+	assert.NoError(t, lvl.UnmarshalText(nil))
+	assert.Equal(t, Uninitialized, lvl)
+	assert.Error(t, lvl.UnmarshalText([]byte("xx")))
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"context"
+	"os"
+	"strings"
+)
+
 // -----------------------------
 // ---------Manager API---------
 // -----------------------------
@@ -10,6 +16,12 @@ type FormatterFuncCreator func(cfg CfgFormat) (Formatter, error)
 // OutputFuncCreator is function will to create a Output instance by configuration
 type OutputFuncCreator func(cfg CfgOutput) (Output, error)
 
+// ErrorHandlerFunc is function will to handle the error of writing the output named output
+type ErrorHandlerFunc func(output string, err error)
+
+// ContextExtractorFunc is function will to extract fields from the context of a event
+type ContextExtractorFunc func(ctx context.Context) []Field
+
 // Manager is the configurations and creators holder
 type Manager interface {
 	// RegisterFormatterCreator ..
@@ -18,9 +30,31 @@ type Manager interface {
 	// RegisterOutputCreator ..
 	RegisterOutputCreator(stype string, o OutputFuncCreator)
 
+	// RegisterFilterCreator ..
+	RegisterFilterCreator(stype string, f FilterFuncCreator)
+
+	// RegisterContextExtractor register a extractor by name, the fields extracted from the context
+	// of each event are appended to it, nil extractor removes the registered one. The extractor named
+	// 'traceparent' is registered by default, it extracts trace_id and span_id from the W3C traceparent
+	// carried by WithTraceparent.
+	RegisterContextExtractor(name string, e ContextExtractorFunc)
+
+	// SetErrorHandler set the handler of the errors of outputs, nil restores the default one
+	// which prints the errors to stderr at most once per second for each output.
+	SetErrorHandler(h ErrorHandlerFunc)
+
+	// SetStatusLogger set the logger which records the internal problems of log4g like the errors
+	// of outputs besides the error handler, nil disables it. The problems are recorded asynchronously
+	// and dropped if too many are pending, the ones occurred when writing only the status records are
+	// not recorded again.
+	SetStatusLogger(l Logger)
+
 	// GetLoggerOutputs ..
 	GetLoggerOutputs(name string) (ops []Output, lvl Level, err error)
 
+	// GetLoggerFilters return the filters attached to the logger by configuration
+	GetLoggerFilters(name string) (fs []Filter, err error)
+
 	// LoadConfigFile ..
 	LoadConfigFile(file string) error
 
@@ -30,10 +64,77 @@ type Manager interface {
 	// SetConfig ..
 	SetConfig(cfg *Config) error
 
+	// SetLevel override the level of loggers matched by pattern and all their descendants,
+	// pattern is a logger name or a glob like 'a/*', the override survives the config
+	// reloading until ResetLevel is called.
+	SetLevel(pattern string, lvl Level)
+
+	// ResetLevel remove the level override of pattern which set by SetLevel
+	ResetLevel(pattern string)
+
+	// LevelOverrides return a copy of the active level overrides, key is the pattern
+	LevelOverrides() map[string]Level
+
+	// Loggers return the snapshots of all created loggers, sorted by name
+	Loggers() []LoggerInfo
+
+	// Outputs return the snapshots of all configured outputs, sorted by name
+	Outputs() []OutputInfo
+
+	// Formats return the snapshots of all configured formats, sorted by name
+	Formats() []FormatInfo
+
+	// Config return a copy of the active configuration
+	Config() *Config
+
+	// Flush all outputs which buffer events.
+	Flush()
+
 	// Close all output and wait all event write to outputs.
 	Close()
 }
 
+// InitOptions controls the initialization of the global manager
+type InitOptions struct {
+	// ConfigFile is loaded as the configuration, it takes precedence over Config
+	ConfigFile string
+
+	// Config is applied as the configuration, the current one is kept if both are not set
+	Config *Config
+
+	// CloseSignals close the global manager when received, the signal handling is
+	// removed after closing, so a second signal terminates the process as default.
+	CloseSignals []os.Signal
+
+	// FlushSignals flush all outputs of the global manager when received
+	FlushSignals []os.Signal
+}
+
+// LoggerInfo is the snapshot of a logger
+type LoggerInfo struct {
+	Name           string   `json:"name"`
+	Level          Level    `json:"level"`           // the level set by config or Logger.SetLevel
+	EffectiveLevel Level    `json:"effective_level"` // the level after inheriting and overriding
+	Outputs        []string `json:"outputs"`         // the names of effective outputs
+}
+
+// OutputInfo is the snapshot of a output
+type OutputInfo struct {
+	Name    string       `json:"name"`
+	Type    string       `json:"type"`
+	Config  CfgOutput    `json:"config"`
+	Created bool         `json:"created"` // whether the output is created by a logger referenced it
+	Stats   *OutputStats `json:"stats,omitempty"`
+}
+
+// FormatInfo is the snapshot of a formatter
+type FormatInfo struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	Config  CfgFormat `json:"config"`
+	Created bool      `json:"created"` // whether the formatter is created by a output referenced it
+}
+
 // -----------------------------
 // ---------Config API----------
 // -----------------------------
@@ -41,10 +142,31 @@ type Manager interface {
 // Config struct aggregates all formatter, output and logger configurations
 type Config struct {
 	Formats []CfgFormat `yaml:"formats" json:"formats"`
+	Filters []CfgFilter `yaml:"filters" json:"filters"`
 	Outputs []CfgOutput `yaml:"outputs" json:"outputs"`
 	Loggers []CfgLogger `yaml:"loggers" json:"loggers"`
 }
 
+// Clone return a deep copy of the configuration
+func (c *Config) Clone() *Config {
+	n := &Config{}
+	for _, f := range c.Formats {
+		n.Formats = append(n.Formats, f.Clone())
+	}
+	for _, f := range c.Filters {
+		n.Filters = append(n.Filters, f.Clone())
+	}
+	for _, o := range c.Outputs {
+		n.Outputs = append(n.Outputs, o.Clone())
+	}
+	for _, l := range c.Loggers {
+		l.OutputNames = append([]string(nil), l.OutputNames...)
+		l.FilterNames = append([]string(nil), l.FilterNames...)
+		n.Loggers = append(n.Loggers, l)
+	}
+	return n
+}
+
 // GetCfgLogger return the point of CfgLogger which matched by name
 func (c *Config) GetCfgLogger(name string) *CfgLogger {
 	for _, l := range c.Loggers {
@@ -75,11 +197,22 @@ func (c *Config) GetCfgFormat(name string) CfgFormat {
 	return nil
 }
 
+// GetCfgFilter return the point of CfgFilter which matched by name
+func (c *Config) GetCfgFilter(name string) CfgFilter {
+	for _, l := range c.Filters {
+		if l.Name() == name {
+			return l
+		}
+	}
+	return nil
+}
+
 // CfgLogger represents the configuration of a logger
 type CfgLogger struct {
 	Name        string   `yaml:"name" json:"name"`
 	Level       string   `yaml:"level" json:"level"`
 	OutputNames []string `yaml:"outputs" json:"outputs"`
+	FilterNames []string `yaml:"filters" json:"filters"`
 }
 
 // CfgOutput represents the configuration of a output
@@ -100,6 +233,41 @@ func (c CfgOutput) FormatName() string {
 	return c["format"]
 }
 
+// FilterNames return the names of filters separated by comma
+func (c CfgOutput) FilterNames() []string {
+	return splitNames(c["filters"])
+}
+
+// Names return the names separated by comma of the key, like the 'secondaries' of the failover output
+func (c CfgOutput) Names(key string) []string {
+	return splitNames(c[key])
+}
+
+func splitNames(str string) []string {
+	var names []string
+	for _, n := range strings.Split(str, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+// MarkerNames return the names of markers separated by comma, the events with one of
+// the markers are routed to the output regardless of the logger
+func (c CfgOutput) MarkerNames() []string {
+	return splitNames(c["markers"])
+}
+
+// Clone return a copy of the configuration
+func (c CfgOutput) Clone() CfgOutput {
+	n := make(CfgOutput, len(c))
+	for k, v := range c {
+		n[k] = v
+	}
+	return n
+}
+
 // CfgFormat represents the configuration of a formatter
 type CfgFormat map[string]string
 
@@ -112,3 +280,12 @@ func (c CfgFormat) Name() string {
 func (c CfgFormat) Type() string {
 	return c["type"]
 }
+
+// Clone return a copy of the configuration
+func (c CfgFormat) Clone() CfgFormat {
+	n := make(CfgFormat, len(c))
+	for k, v := range c {
+		n[k] = v
+	}
+	return n
+}
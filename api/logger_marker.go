@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Marker is a named tag of events like the marker of log4j, a marker can have parents,
+// so a event tagged by a marker is also tagged by all its ancestors.
+type Marker struct {
+	name    string
+	parents atomic.Value // []*Marker
+}
+
+var (
+	markerLock sync.Mutex
+	markers    sync.Map // key: name, value: *Marker
+)
+
+// GetMarker return the marker of the name, it is created if not exists
+func GetMarker(name string) *Marker {
+	if m, ok := markers.Load(name); ok {
+		return m.(*Marker)
+	}
+	m := &Marker{name: name}
+	m.parents.Store([]*Marker(nil))
+	v, _ := markers.LoadOrStore(name, m)
+	return v.(*Marker)
+}
+
+// LookupMarker return the marker of the name and whether it exists, it is not created if not exists
+func LookupMarker(name string) (*Marker, bool) {
+	if m, ok := markers.Load(name); ok {
+		return m.(*Marker), true
+	}
+	return nil, false
+}
+
+// Name return the name of the marker
+func (m *Marker) Name() string {
+	return m.name
+}
+
+// String return the name of the marker
+func (m *Marker) String() string {
+	return m.name
+}
+
+// AddParents add the parents to the marker, the parent which is a descendant of the marker is ignored
+func (m *Marker) AddParents(parents ...*Marker) *Marker {
+	markerLock.Lock()
+	defer markerLock.Unlock()
+	ps := append([]*Marker(nil), m.Parents()...)
+	for _, p := range parents {
+		if p != nil && !p.IsInstanceOf(m.name) && !m.hasParent(p) {
+			ps = append(ps, p)
+		}
+	}
+	m.parents.Store(ps)
+	return m
+}
+
+// Parents return the parents of the marker
+func (m *Marker) Parents() []*Marker {
+	return m.parents.Load().([]*Marker)
+}
+
+// IsInstanceOf return whether the marker or one of its ancestors has the name
+func (m *Marker) IsInstanceOf(name string) bool {
+	if m.name == name {
+		return true
+	}
+	for _, p := range m.Parents() {
+		if p.IsInstanceOf(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Marker) hasParent(p *Marker) bool {
+	for _, v := range m.Parents() {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
+type markersKey struct{}
+
+// WithMarkers return a copy of ctx which carries the markers appended to the ones carried by ctx,
+// the markers are rendered by the %{marker} verb, used to filter events by the filter of type
+// 'marker', and route events to the outputs configured with 'markers'.
+func WithMarkers(ctx context.Context, markers ...string) context.Context {
+	if len(markers) == 0 {
+		return ctx
+	}
+	prev := MarkersFrom(ctx)
+	all := make([]string, 0, len(prev)+len(markers))
+	all = append(append(all, prev...), markers...)
+	return context.WithValue(ctx, markersKey{}, all)
+}
+
+// MarkersFrom return the names of markers carried by ctx
+func MarkersFrom(ctx context.Context) []string {
+	markers, _ := ctx.Value(markersKey{}).([]string)
+	return markers
+}
+
+// HasMarker return whether one of the markers carried by ctx is the instance of the name,
+// the markers not created by GetMarker have no parents and are not created by it
+func HasMarker(ctx context.Context, name string) bool {
+	for _, m := range MarkersFrom(ctx) {
+		if m == name {
+			return true
+		}
+		if mk, ok := LookupMarker(m); ok && mk.IsInstanceOf(name) {
+			return true
+		}
+	}
+	return false
+}
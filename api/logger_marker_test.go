@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarker(t *testing.T) {
+	audit := GetMarker("test.AUDIT")
+	login := GetMarker("test.LOGIN").AddParents(audit, GetMarker("test.SECURITY"))
+	assert.Equal(t, login, GetMarker("test.LOGIN"))
+	assert.Equal(t, "test.LOGIN", login.Name())
+	assert.True(t, login.IsInstanceOf("test.AUDIT"))
+	assert.True(t, login.IsInstanceOf("test.SECURITY"))
+	assert.False(t, audit.IsInstanceOf("test.LOGIN"))
+
+	// the cycle and duplicated parents are ignored
+	audit.AddParents(login)
+	login.AddParents(audit)
+	assert.Empty(t, audit.Parents())
+	assert.Len(t, login.Parents(), 2)
+
+	ctx := WithMarkers(context.Background(), "test.LOGIN")
+	ctx = WithMarkers(ctx, "test.OTHER")
+	assert.Equal(t, []string{"test.LOGIN", "test.OTHER"}, MarkersFrom(ctx))
+	assert.True(t, HasMarker(ctx, "test.AUDIT"))
+	assert.False(t, HasMarker(context.Background(), "test.AUDIT"))
+
+	// the lookup does not create the markers
+	assert.True(t, HasMarker(ctx, "test.OTHER"))
+	assert.False(t, HasMarker(ctx, "test.NONE"))
+	_, ok := LookupMarker("test.OTHER")
+	assert.False(t, ok)
+	_, ok = LookupMarker("test.NONE")
+	assert.False(t, ok)
+}
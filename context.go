@@ -0,0 +1,40 @@
+package log4g
+
+import (
+	"context"
+
+	"github.com/xtfly/log4g/api"
+	"github.com/xtfly/log4g/internal"
+)
+
+type writerKey struct{}
+
+// NewContext return a copy of ctx which carries the writer, it is usually a Logger or
+// a Writer with request-scoped fields created by Logger.WithFields.
+func NewContext(ctx context.Context, w api.Writer) context.Context {
+	return context.WithValue(ctx, writerKey{}, w)
+}
+
+// FromContext return the writer carried by ctx, or the logger of the global context named fallbackName
+// if not carried, the returned writer writes events with ctx and the fields of the carried writer.
+// Use FromContextFactory to fall back to a logger of a LoggerContext.
+func FromContext(ctx context.Context, fallbackName string) api.Writer {
+	return FromContextFactory(ctx, globalFactory{}, fallbackName)
+}
+
+// FromContextFactory is like FromContext, but the fallback logger is got from the factory f,
+// which is usually a LoggerContext created by NewLoggerContext.
+func FromContextFactory(ctx context.Context, f api.Factory, fallbackName string) api.Writer {
+	w, ok := ctx.Value(writerKey{}).(api.Writer)
+	if !ok {
+		w = f.GetLogger(fallbackName)
+	}
+	return internal.WithContext(w, ctx)
+}
+
+// globalFactory get the loggers of the global context
+type globalFactory struct{}
+
+func (globalFactory) GetLogger(name string) api.Logger {
+	return GetLogger(name)
+}
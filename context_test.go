@@ -0,0 +1,28 @@
+package log4g
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestContext(t *testing.T) {
+	lctx, op := newTestContext(t, "%{module}|%{req}|%{user}|%{msg}\n")
+	defer lctx.Close()
+
+	w := lctx.GetLogger("ctx").WithFields(api.Field{Key: "req", Value: 1})
+	ctx := NewContext(context.WithValue(context.Background(), "user", "bob"), w)
+	FromContext(ctx, "fallback").Info("hello")
+	assert.Equal(t, "ctx|1|bob|hello\n", op.String())
+
+	ctx = NewContext(ctx, lctx.GetLogger("ctx2"))
+	FromContext(ctx, "fallback").Info("world")
+	assert.Equal(t, "ctx|1|bob|hello\nctx2|<nil>|bob|world\n", op.String())
+
+	assert.NotNil(t, FromContext(context.Background(), "fallback"))
+
+	FromContextFactory(context.Background(), lctx, "fallback").Info("again")
+	assert.Equal(t, "ctx|1|bob|hello\nctx2|<nil>|bob|world\nfallback|<nil>|<nil>|again\n", op.String())
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"github.com/xtfly/log4g/api"
+)
+
+// matchFilter is the filter which returns the configured results on matching or not,
+// the builtin filters share it with different match functions.
+type matchFilter struct {
+	onMatch    api.FilterResult
+	onMismatch api.FilterResult
+	match      func(e *api.Event) bool
+}
+
+// newMatchFilter return a matchFilter with the results configured by 'on_match' and 'on_mismatch',
+// the default results are NEUTRAL on matching and DENY on mismatching.
+func newMatchFilter(cfg api.CfgFilter, match func(e *api.Event) bool) (api.Filter, error) {
+	var err error
+	f := &matchFilter{onMatch: api.Neutral, onMismatch: api.Deny, match: match}
+	if s, ok := cfg["on_match"]; ok {
+		if f.onMatch, err = api.FilterResultFrom(s); err != nil {
+			return nil, err
+		}
+	}
+	if s, ok := cfg["on_mismatch"]; ok {
+		if f.onMismatch, err = api.FilterResultFrom(s); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Filter return the result by matching the event
+func (f *matchFilter) Filter(e *api.Event) api.FilterResult {
+	if f.match(e) {
+		return f.onMatch
+	}
+	return f.onMismatch
+}
+
+// filterChain evaluate the filters in order, return the first result which is not NEUTRAL
+func filterChain(fs []api.Filter, e *api.Event) api.FilterResult {
+	for _, f := range fs {
+		if r := f.Filter(e); r != api.Neutral {
+			return r
+		}
+	}
+	return api.Neutral
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	typeLevelRange = "level_range"
+	typeRegex      = "regex"
+	typeField      = "field"
+	typeLoggerName = "logger"
+	typeMarker     = "marker"
+)
+
+// NewLevelRangeFilter return a Filter instance which matches the events with the level
+// between 'min_level' and 'max_level', both are inclusive and optional.
+func NewLevelRangeFilter(cfg api.CfgFilter) (api.Filter, error) {
+	min, max := api.All, api.Off
+	if s, ok := cfg["min_level"]; ok {
+		if min = api.LevelFrom(s); min == api.Uninitialized {
+			return nil, fmt.Errorf("invalid min_level %q of filter[%s]", s, cfg.Name())
+		}
+	}
+	if s, ok := cfg["max_level"]; ok {
+		if max = api.LevelFrom(s); max == api.Uninitialized {
+			return nil, fmt.Errorf("invalid max_level %q of filter[%s]", s, cfg.Name())
+		}
+	}
+	return newMatchFilter(cfg, func(e *api.Event) bool {
+		return e.Level >= min && e.Level <= max
+	})
+}
+
+// NewRegexFilter return a Filter instance which matches the events with the message matched by 'regex'
+func NewRegexFilter(cfg api.CfgFilter) (api.Filter, error) {
+	re, err := regexp.Compile(cfg["regex"])
+	if err != nil {
+		return nil, err
+	}
+	return newMatchFilter(cfg, func(e *api.Event) bool {
+		return re.MatchString(e.Message())
+	})
+}
+
+// NewFieldFilter return a Filter instance which matches the events with the field 'key'
+// equals to 'value', or matched by 'regex' if it is set, the fields are the ones carried
+// by WithFields or extracted by the context extractors.
+func NewFieldFilter(cfg api.CfgFilter) (api.Filter, error) {
+	key := cfg["key"]
+	if key == "" {
+		return nil, fmt.Errorf("not set key of filter[%s]", cfg.Name())
+	}
+
+	match := func(v string) bool { return v == cfg["value"] }
+	if s, ok := cfg["regex"]; ok {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+		match = re.MatchString
+	}
+	return newMatchFilter(cfg, func(e *api.Event) bool {
+		v := e.Ctx.Value(key)
+		return v != nil && match(fmt.Sprint(v))
+	})
+}
+
+// NewLoggerNameFilter return a Filter instance which matches the events of the loggers
+// with the name prefixed by 'prefix'
+func NewLoggerNameFilter(cfg api.CfgFilter) (api.Filter, error) {
+	prefix := cfg["prefix"]
+	return newMatchFilter(cfg, func(e *api.Event) bool {
+		return strings.HasPrefix(e.Name, prefix)
+	})
+}
+
+// NewMarkerFilter return a Filter instance which matches the events with the 'marker'
+// or its descendants carried by WithMarkers
+func NewMarkerFilter(cfg api.CfgFilter) (api.Filter, error) {
+	marker := cfg["marker"]
+	if marker == "" {
+		return nil, fmt.Errorf("not set marker of filter[%s]", cfg.Name())
+	}
+	return newMatchFilter(cfg, func(e *api.Event) bool {
+		return api.HasMarker(e.Ctx, marker)
+	})
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestBuiltinFilters(t *testing.T) {
+	ctx := api.WithMarkers(api.WithFields(context.Background(), api.Field{Key: "user", Value: "bob"}), "audit")
+	evt := &api.Event{Name: "a/b", Level: api.Info, Format: "hello %s", Arguments: []interface{}{"world"}, Ctx: ctx}
+
+	cases := []struct {
+		create api.FilterFuncCreator
+		cfg    api.CfgFilter
+		result api.FilterResult
+	}{
+		{NewLevelRangeFilter, api.CfgFilter{"min_level": "debug", "max_level": "warn"}, api.Neutral},
+		{NewLevelRangeFilter, api.CfgFilter{"min_level": "error"}, api.Deny},
+		{NewLevelRangeFilter, api.CfgFilter{"max_level": "info", "on_match": "accept"}, api.Accept},
+		{NewRegexFilter, api.CfgFilter{"regex": "^hello w"}, api.Neutral},
+		{NewRegexFilter, api.CfgFilter{"regex": "bye", "on_mismatch": "neutral"}, api.Neutral},
+		{NewFieldFilter, api.CfgFilter{"key": "user", "value": "bob", "on_match": "deny"}, api.Deny},
+		{NewFieldFilter, api.CfgFilter{"key": "user", "regex": "^a"}, api.Deny},
+		{NewFieldFilter, api.CfgFilter{"key": "none", "value": ""}, api.Deny},
+		{NewLoggerNameFilter, api.CfgFilter{"prefix": "a/", "on_match": "accept"}, api.Accept},
+		{NewLoggerNameFilter, api.CfgFilter{"prefix": "b"}, api.Deny},
+		{NewMarkerFilter, api.CfgFilter{"marker": "audit", "on_match": "accept"}, api.Accept},
+		{NewMarkerFilter, api.CfgFilter{"marker": "x"}, api.Deny},
+	}
+	for _, c := range cases {
+		f, err := c.create(c.cfg)
+		assert.NoError(t, err, c.cfg)
+		assert.Equal(t, c.result, f.Filter(evt), c.cfg)
+	}
+
+	for _, c := range []struct {
+		create api.FilterFuncCreator
+		cfg    api.CfgFilter
+	}{
+		{NewLevelRangeFilter, api.CfgFilter{"min_level": "x"}},
+		{NewLevelRangeFilter, api.CfgFilter{"on_match": "x"}},
+		{NewRegexFilter, api.CfgFilter{"regex": "("}},
+		{NewFieldFilter, api.CfgFilter{"value": "x"}},
+		{NewMarkerFilter, api.CfgFilter{}},
+	} {
+		f, err := c.create(c.cfg)
+		assert.Error(t, err, c.cfg)
+		assert.Nil(t, f, c.cfg)
+	}
+}
+
+func TestFilterChain(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "all", OutputNames: []string{"m1"}, FilterNames: []string{"no_trace"}},
+			{Name: "audit", Level: "all", OutputNames: []string{"m1"}, FilterNames: []string{"marked", "no_trace"}},
+		},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{module}|%{msg}\n"}},
+		Filters: []api.CfgFilter{
+			{"type": "level_range", "name": "no_trace", "min_level": "debug"},
+			{"type": "marker", "name": "marked", "marker": "audit", "on_match": "accept", "on_mismatch": "neutral"},
+			{"type": "regex", "name": "no_secret", "regex": "secret", "on_match": "deny", "on_mismatch": "neutral"},
+		},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1", "filters": "no_secret"}},
+	})
+	assert.NoError(t, err)
+
+	log := ctx.GetLogger("a/b")
+	log.Trace("trace")
+	log.Debug("debug")
+	log.Info("secret")
+
+	audit := ctx.GetLogger("audit")
+	audit.WithCtx(api.WithMarkers(context.Background(), "audit")).Trace("marked trace")
+	audit.Trace("trace")
+
+	mo := ctx.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "a/b|debug\naudit|marked trace\n", mo.String())
+	assert.Equal(t, uint64(1), mo.Stats().Dropped)
+
+	err = ctx.Manager().SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"m1"}, FilterNames: []string{"f"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+	})
+	assert.Error(t, err)
+}
+
+// closeOutput is a output which not supports filters and records whether closed
+type closeOutput struct {
+	closed bool
+}
+
+func (o *closeOutput) Send(_ *api.Event)            {}
+func (o *closeOutput) SetFormatter(_ api.Formatter) {}
+func (o *closeOutput) CallerInfoFlag() int          { return ciNoneFlog }
+func (o *closeOutput) Close()                       { o.closed = true }
+
+func TestFilterReload(t *testing.T) {
+	newCfg := func(minLevel string) *api.Config {
+		return &api.Config{
+			Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"m1"}, FilterNames: []string{"ft"}}},
+			Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+			Filters: []api.CfgFilter{{"type": "level_range", "name": "ft", "min_level": minLevel}},
+			Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+		}
+	}
+	ctx, err := NewLoggerContext(newCfg("debug"))
+	assert.NoError(t, err)
+	log := ctx.GetLogger("a")
+	log.Debug("1")
+
+	// the filter is created again with the new parameters
+	assert.NoError(t, ctx.Manager().SetConfig(newCfg("warn")))
+	log.Debug("2")
+	log.Warn("3")
+	mo := ctx.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "1\n3\n", mo.String())
+
+	// the output created is closed if its filters can not be set
+	op := &closeOutput{}
+	ctx.Manager().RegisterOutputCreator("close", func(_ api.CfgOutput) (api.Output, error) {
+		return op, nil
+	})
+	cfg := newCfg("warn")
+	cfg.Outputs = append(cfg.Outputs, api.CfgOutput{"type": "close", "name": "c1", "format": "f1", "filters": "ft"})
+	cfg.Loggers = append(cfg.Loggers, api.CfgLogger{Name: "b", Level: "all", OutputNames: []string{"c1"}})
+	assert.NoError(t, ctx.Manager().SetConfig(cfg))
+	_, _, err = ctx.Manager().GetLoggerOutputs("b")
+	assert.Error(t, err)
+	assert.True(t, op.closed)
+	ctx.Close()
+}
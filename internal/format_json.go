@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	typeJSON    = "json"
+	fieldNDC    = "ndc"
+	fieldMarker = "marker"
+
+	// jsonFieldPrefix prefixes the key of a field which is the same as a fixed key
+	jsonFieldPrefix = "fields."
+)
+
+type jsonFormatter struct {
+	timeLayout string
+	caller     bool
+}
+
+// NewJSONFormatter return a Formatter instance which formats a event to a JSON object per line,
+// the object has the keys time, level, module, msg, caller(optional) and the fields of the event,
+// the key of a field is prefixed by 'fields.' if it is one of the fixed keys.
+func NewJSONFormatter(cfg api.CfgFormat) (api.Formatter, error) {
+	f := &jsonFormatter{
+		timeLayout: cfg["time_layout"],
+		caller:     cfg["caller"] == "true",
+	}
+	if f.timeLayout == "" {
+		f.timeLayout = defaultTimeLayout
+	}
+	return f, nil
+}
+
+// Format a logger event to a JSON line
+func (f *jsonFormatter) Format(e *api.Event) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSONValue(&buf, e.Time.Format(f.timeLayout))
+	buf.WriteString(`,"level":`)
+	writeJSONValue(&buf, e.Level.String())
+	buf.WriteString(`,"module":`)
+	writeJSONValue(&buf, e.Name)
+	buf.WriteString(`,"msg":`)
+	writeJSONValue(&buf, e.Message())
+	if f.caller {
+		ci := getCallerInfo(e, false)
+		buf.WriteString(`,"caller":`)
+		writeJSONValue(&buf, filepath.Base(ci.file)+":"+strconv.Itoa(ci.line))
+	}
+
+	fields := diagFields(e.Ctx)
+	if markers := api.MarkersFrom(e.Ctx); len(markers) != 0 {
+		fields = append(fields, api.Field{Key: fieldMarker, Value: strings.Join(markers, ",")})
+	}
+	fields = append(fields, api.FieldsFrom(e.Ctx)...)
+	for i := range fields {
+		// the later field overrides the former one with the same key
+		if lastFieldIndex(fields, fields[i].Key) != i {
+			continue
+		}
+		key := fields[i].Key
+		if f.fixedKey(key) {
+			key = jsonFieldPrefix + key
+		}
+		buf.WriteByte(',')
+		writeJSONValue(&buf, key)
+		buf.WriteByte(':')
+		writeJSONValue(&buf, fields[i].Value)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+// fixedKey return whether the key is written by the formatter before the fields
+func (f *jsonFormatter) fixedKey(key string) bool {
+	switch key {
+	case "time", "level", "module", "msg":
+		return true
+	case "caller":
+		return f.caller
+	}
+	return false
+}
+
+// CallerInfoFlag return the file flag if the caller is required
+func (f *jsonFormatter) CallerInfoFlag() int {
+	if f.caller {
+		return ciFileFlag
+	}
+	return ciNoneFlog
+}
+
+// diagFields return the mapped diagnostic context as fields and the nested one as the field 'ndc'
+func diagFields(ctx context.Context) []api.Field {
+	dc := api.DiagContextFrom(ctx)
+	if dc == nil {
+		return nil
+	}
+	keys := dc.MDCKeys()
+	fields := make([]api.Field, 0, len(keys)+1)
+	for _, k := range keys {
+		v, _ := dc.MDC(k)
+		fields = append(fields, api.Field{Key: k, Value: v})
+	}
+	if ndc := dc.NDC(); len(ndc) != 0 {
+		fields = append(fields, api.Field{Key: fieldNDC, Value: strings.Join(ndc, " ")})
+	}
+	return fields
+}
+
+func lastFieldIndex(fields []api.Field, key string) int {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeJSONValue write the JSON encoding of v, the error and the value can not be encoded are written as string
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		bs, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(bs)
+}
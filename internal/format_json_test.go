@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestJSONFormat(t *testing.T) {
+	f, err := NewJSONFormatter(api.CfgFormat{"type": "json", "name": "j1", "time_layout": "2006-01-02", "caller": "true"})
+	assert.NoError(t, err)
+	assert.Equal(t, ciFileFlag, f.CallerInfoFlag())
+
+	ctx := api.WithFields(context.Background(),
+		api.Field{Key: "a", Value: 1}, api.Field{Key: "err", Value: errors.New("failed")},
+		api.Field{Key: "a", Value: "x"}, api.Field{Key: "ch", Value: make(chan int)})
+	fbs := f.Format(&api.Event{
+		Format:    "hello %s",
+		Arguments: []interface{}{"\"world\""},
+		Name:      "module",
+		Level:     api.Info,
+		Time:      time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		Ctx:       ctx,
+		CallDepth: 1,
+	})
+	assert.Regexp(t, `^\{"time":"2020-01-02","level":"INFO","module":"module","msg":"hello \\"world\\"",`+
+		`"caller":"format_json_test.go:\d+","err":"failed","a":"x","ch":"0x[0-9a-f]+"\}\n$`, string(fbs))
+}
+
+func TestJSONFormatDiagContext(t *testing.T) {
+	f, _ := NewJSONFormatter(api.CfgFormat{"type": "json", "name": "j1", "time_layout": "2006"})
+
+	ctx := api.PutMDC(api.PushNDC(context.Background(), "outer"), "user", "bob")
+	ctx = api.WithFields(ctx, api.Field{Key: "user", Value: "alice"})
+	fbs := f.Format(&api.Event{Format: "hi", Level: api.Info, Ctx: ctx})
+	assert.Equal(t, `{"time":"0001","level":"INFO","module":"","msg":"hi","ndc":"outer","user":"alice"}`+"\n", string(fbs))
+}
+
+func TestJSONFormatFixedKeys(t *testing.T) {
+	f, _ := NewJSONFormatter(api.CfgFormat{"type": "json", "name": "j1", "time_layout": "2006", "caller": "true"})
+
+	ctx := api.PutMDC(context.Background(), "level", "x")
+	ctx = api.WithFields(ctx, api.Field{Key: "msg", Value: "y"}, api.Field{Key: "caller", Value: "z"},
+		api.Field{Key: "time", Value: 1}, api.Field{Key: "module", Value: "m"})
+	fbs := f.Format(&api.Event{Format: "hi", Level: api.Info, Ctx: ctx, CallDepth: 1})
+	assert.Regexp(t, `^\{"time":"0001","level":"INFO","module":"","msg":"hi","caller":"format_json_test.go:\d+",`+
+		`"fields.level":"x","fields.msg":"y","fields.caller":"z","fields.time":1,"fields.module":"m"\}\n$`, string(fbs))
+}
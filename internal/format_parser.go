@@ -27,6 +27,7 @@ const (
 
 const (
 	verbTime   = "time"
+	verbMDC    = "mdc"
 	verbExtend = "_extend"
 
 	defaultTimeLayout = "2006-01-02T15:04:05.000Z07:00"
@@ -55,7 +56,7 @@ type StringFormatter struct {
 	parts []*part
 }
 
-var formatRe = regexp.MustCompile(`%{([a-zA-Z0-9]+)(?::(.*?[^\\]))?}`)
+var formatRe = regexp.MustCompile(`%{([a-zA-Z0-9_.]+)(?::(.*?[^\\]))?}`)
 
 var (
 	formatFuncs = map[string]FormatFunc{
@@ -72,6 +73,11 @@ var (
 		"shortpkg":  shortpkgFormatFunc,
 		"longfunc":  longfuncFormatFunc,
 		"shortfunc": shortfuncFormatFunc,
+		"trace_id":  traceIDFormatFunc,
+		"span_id":   spanIDFormatFunc,
+		"ndc":       ndcFormatFunc,
+		"marker":    markerFormatFunc,
+		verbMDC:     mdcFormatFunc,
 		verbTime:    timeFormatFunc,
 		verbExtend:  extendFormatFunc,
 	}
@@ -101,6 +107,12 @@ var (
 //     %{msg}       Message (string)
 //     %{longfile}  Full file name and line number: /a/b/c/d.go:23
 //     %{shortfile} Final file name element and line number: d.go:23
+//     %{trace_id}  Trace id extracted from the W3C traceparent, empty if absent
+//     %{span_id}   Span id extracted from the W3C traceparent, empty if absent
+//     %{mdc:key}   Value of the key in the mapped diagnostic context, empty if absent
+//     %{mdc}       Mapped diagnostic context: k1=v1,k2=v2
+//     %{ndc}       Nested diagnostic context separated by space: outer inner
+//     %{marker}    Markers separated by comma: AUDIT,SECURITY
 //
 // For normal types, the output can be customized by using the 'verbs' defined
 // in the fmt package, eg. '%{id:04d}' to make the id output be '%04d' as the
@@ -148,6 +160,9 @@ func (f *StringFormatter) Parser(layout string) error {
 			if name == verbTime {
 				part.verbType = fmtVerbTime
 				part.layout = layout[m[4]:m[5]]
+			} else if name == verbMDC {
+				// the key of mdc, not a fmt verb
+				part.layout = layout[m[4]:m[5]]
 			} else {
 				part.fmtStr = "%" + layout[m[4]:m[5]]
 			}
@@ -262,6 +277,44 @@ func shortfuncFormatFunc(evt *api.Event, _ *part) interface{} {
 	return ci.fun[i+1:]
 }
 
+// %{trace_id} Trace id extracted from the W3C traceparent
+func traceIDFormatFunc(evt *api.Event, _ *part) interface{} {
+	return fieldString(evt, fieldTraceID)
+}
+
+// %{span_id} Span id extracted from the W3C traceparent
+func spanIDFormatFunc(evt *api.Event, _ *part) interface{} {
+	return fieldString(evt, fieldSpanID)
+}
+
+// fieldString return the field value of the event as string, empty if absent
+func fieldString(evt *api.Event, key string) string {
+	if s, ok := evt.Ctx.Value(key).(string); ok {
+		return s
+	}
+	return ""
+}
+
+// %{mdc:key} value of key in the mapped diagnostic context, %{mdc} all mapped diagnostic context
+func mdcFormatFunc(evt *api.Event, part *part) interface{} {
+	dc := api.DiagContextFrom(evt.Ctx)
+	if part.layout == "" {
+		return dc.String()
+	}
+	v, _ := dc.MDC(part.layout)
+	return v
+}
+
+// %{ndc} nested diagnostic context separated by space
+func ndcFormatFunc(evt *api.Event, _ *part) interface{} {
+	return strings.Join(api.DiagContextFrom(evt.Ctx).NDC(), " ")
+}
+
+// %{marker} markers separated by comma
+func markerFormatFunc(evt *api.Event, _ *part) interface{} {
+	return strings.Join(api.MarkersFrom(evt.Ctx), ",")
+}
+
 // %{time} Time when log occurred (time.Time)
 func timeFormatFunc(evt *api.Event, part *part) interface{} {
 	if part.layout == "" {
@@ -26,7 +26,7 @@ func TestFormat(t *testing.T) {
 
 func TestFormat2(t *testing.T) {
 	var buf bytes.Buffer
-	op := NewBaseOutput(&buf, api.All)
+	op := newBaseOutput(&buf, api.All)
 	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1",
 		"layout": "%{shortfile}"})
 	op.SetFormatter(f)
@@ -50,7 +50,7 @@ func (structFunc) Log(log api.Logger) {
 
 func TestFormat3(t *testing.T) {
 	var buf bytes.Buffer
-	op := NewBaseOutput(&buf, api.All)
+	op := newBaseOutput(&buf, api.All)
 	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1",
 		"layout": "%{shortfunc}"})
 	op.SetFormatter(f)
@@ -64,7 +64,7 @@ func TestFormat3(t *testing.T) {
 
 func TestFormat4(t *testing.T) {
 	var buf bytes.Buffer
-	op := NewBaseOutput(&buf, api.All)
+	op := newBaseOutput(&buf, api.All)
 	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1",
 		"layout": "%{longfunc}"})
 	op.SetFormatter(f)
@@ -78,7 +78,7 @@ func TestFormat4(t *testing.T) {
 
 func TestFormat5(t *testing.T) {
 	var buf bytes.Buffer
-	op := NewBaseOutput(&buf, api.All)
+	op := newBaseOutput(&buf, api.All)
 	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1",
 		"layout": "%{shortfunc}"})
 	op.SetFormatter(f)
@@ -93,3 +93,16 @@ func TestFormat5(t *testing.T) {
 
 	assert.Equal(t, buf.String(), "func1")
 }
+
+func TestFormatDiagContext(t *testing.T) {
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1",
+		"layout": "[%{ndc}] %{mdc:user}|%{mdc:none}|%{mdc} %{msg}"})
+
+	ctx := api.PushNDC(api.PushNDC(context.Background(), "outer"), "inner")
+	ctx = api.PutMDC(api.PutMDC(ctx, "user", "bob"), "req", "1")
+	fbs := f.Format(&api.Event{Format: "hello", Ctx: ctx})
+	assert.Equal(t, "[outer inner] bob||req=1,user=bob hello", string(fbs))
+
+	fbs = f.Format(&api.Event{Format: "hello", Ctx: context.Background()})
+	assert.Equal(t, "[] || hello", string(fbs))
+}
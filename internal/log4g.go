@@ -1,29 +1,130 @@
 package internal
 
 import (
+	"context"
+	"log"
 	"os"
 	"os/signal"
+	"runtime"
+	"sync"
 	"syscall"
 
 	"github.com/xtfly/log4g/api"
 )
 
+const (
+	// envInit is the environment variable controls the package initialization,
+	// 'manual' means neither config nor signal handling is applied until Init is called.
+	envInit = "LOG4G_INIT"
+	// envConfig is the environment variable of the config file applied at the package initialization
+	envConfig = "LOG4G_CONFIG"
+)
+
 var (
 	gmanager = newManager()
 	gfactory = newFactory(gmanager)
+
+	sigLock sync.Mutex
+	sigStop chan struct{} // close to stop the current signal handling
 )
 
 func init() {
-	gmanager.RegisterFormatterCreator(typeText, NewTextFormatter)
+	registerCreators(gmanager)
+	if os.Getenv(envInit) == "manual" {
+		return
+	}
+
+	opts := api.InitOptions{
+		ConfigFile:   os.Getenv(envConfig),
+		Config:       defaultConfig(),
+		CloseSignals: []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
+	if err := Init(opts); err != nil {
+		log.Println("Warnning: load config failed, use the default config: ", err)
+		opts.ConfigFile = ""
+		_ = Init(opts)
+	}
+}
+
+// Init apply the config and replace the signal handling of the global manager by the options
+func Init(opts api.InitOptions) (err error) {
+	if opts.ConfigFile != "" {
+		err = gmanager.LoadConfigFile(opts.ConfigFile)
+	} else if opts.Config != nil {
+		err = gmanager.SetConfig(opts.Config)
+	}
+	if err != nil {
+		return
+	}
+
+	handleSignals(gmanager, opts.CloseSignals, opts.FlushSignals)
+	return
+}
+
+// handleSignals stop the current signal handling and start a new one if any signals specified
+func handleSignals(m api.Manager, closeSigs []os.Signal, flushSigs []os.Signal) {
+	sigLock.Lock()
+	defer sigLock.Unlock()
+	if sigStop != nil {
+		close(sigStop)
+		sigStop = nil
+	}
+	if len(closeSigs) == 0 && len(flushSigs) == 0 {
+		return
+	}
+
+	closeSet := make(map[os.Signal]bool, len(closeSigs))
+	for _, sig := range closeSigs {
+		closeSet[sig] = true
+	}
+
+	listenSig := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(listenSig, append(append([]os.Signal{}, closeSigs...), flushSigs...)...)
+	sigStop = stop
+	go func() {
+		defer signal.Stop(listenSig)
+		for {
+			select {
+			case <-stop:
+				return
+			case sig := <-listenSig:
+				if closeSet[sig] {
+					m.Close()
+					return
+				}
+				m.Flush()
+			}
+		}
+	}()
+}
+
+// registerCreators register the builtin formatter, filter and output creators to the manager
+func registerCreators(m api.Manager) {
+	m.RegisterFormatterCreator(typeText, NewTextFormatter)
+	m.RegisterFormatterCreator(typeJSON, NewJSONFormatter)
+
+	m.RegisterFilterCreator(typeLevelRange, NewLevelRangeFilter)
+	m.RegisterFilterCreator(typeRegex, NewRegexFilter)
+	m.RegisterFilterCreator(typeField, NewFieldFilter)
+	m.RegisterFilterCreator(typeLoggerName, NewLoggerNameFilter)
+	m.RegisterFilterCreator(typeMarker, NewMarkerFilter)
 
-	gmanager.RegisterOutputCreator(typeConsole, NewConsoleOutput)
-	gmanager.RegisterOutputCreator(typeMemory, NewMemoryOutput)
-	gmanager.RegisterOutputCreator(typeRollingSize, NewRollingOutput)
-	gmanager.RegisterOutputCreator(typeRollingTime, NewRollingOutput)
-	gmanager.RegisterOutputCreator(typeSyslog, NewSyslogOutput)
+	m.RegisterOutputCreator(typeConsole, NewConsoleOutput)
+	m.RegisterOutputCreator(typeMemory, NewMemoryOutput)
+	m.RegisterOutputCreator(typeRollingSize, NewRollingOutput)
+	m.RegisterOutputCreator(typeRollingTime, NewRollingOutput)
+	m.RegisterOutputCreator(typeSyslog, NewSyslogOutput)
+	if dm, ok := m.(*defManager); ok {
+		m.RegisterOutputCreator(typeRouting, newRoutingCreator(dm.outputCreator))
+		m.RegisterOutputCreator(typeFailover, newFailoverCreator(dm.targetOutput))
+		m.RegisterOutputCreator(typeRing, newRingCreator(dm.targetOutput))
+	}
+}
 
-	// default config
-	cfg := &api.Config{
+// defaultConfig return the default config which print all to the console
+func defaultConfig() *api.Config {
+	return &api.Config{
 		Loggers: []api.CfgLogger{
 			{Name: "root", Level: "all", OutputNames: []string{"c1"}},
 		},
@@ -34,14 +135,6 @@ func init() {
 			{"type": "console", "name": "c1", "format": "f1"},
 		},
 	}
-	_ = gmanager.SetConfig(cfg)
-
-	listenSig := make(chan os.Signal, 1)
-	signal.Notify(listenSig, os.Interrupt, syscall.SIGTERM)
-	go func() {
-		<-listenSig
-		gmanager.Close()
-	}()
 }
 
 // GetLogger return the instance that implements Logger interface specified by name,
@@ -55,3 +148,64 @@ func GetLogger(name string) api.Logger {
 func GetManager() api.Manager {
 	return gmanager
 }
+
+type loggerContext struct {
+	api.Factory
+	manager api.Manager
+}
+
+// NewLoggerContext return a self-contained logger context which has its own manager and outputs,
+// the builtin creators are registered and the config is applied, nil config means the default console config.
+func NewLoggerContext(cfg *api.Config) (api.LoggerContext, error) {
+	m := newManager()
+	registerCreators(m)
+	c := &loggerContext{Factory: newFactory(m), manager: m}
+	if cfg == nil {
+		cfg = defaultConfig()
+	}
+	if err := m.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *loggerContext) Manager() api.Manager {
+	return c.manager
+}
+
+func (c *loggerContext) Close() {
+	c.manager.Close()
+}
+
+// Flush flush all outputs of the manager which the logger of the writer belongs to
+func Flush(w api.Writer) {
+	switch v := w.(type) {
+	case *defLogger:
+		v.owner.manager.Flush()
+	case *defWriter:
+		v.logger.owner.manager.Flush()
+	}
+}
+
+// WithContext return a writer which writes events with ctx and the fields of w,
+// the values other than the fields carried by the context of w are not kept.
+func WithContext(w api.Writer, ctx context.Context) api.Writer {
+	switch v := w.(type) {
+	case *defLogger:
+		return &defWriter{logger: v, ctx: ctx}
+	case *defWriter:
+		return &defWriter{logger: v.logger, ctx: api.WithFields(ctx, api.FieldsFrom(v.ctx)...)}
+	}
+	return w
+}
+
+// WithCaller return a writer which has the fields of w and reports the caller info by the frame
+func WithCaller(w api.Writer, frame runtime.Frame) api.Writer {
+	switch v := w.(type) {
+	case *defLogger:
+		return &defWriter{logger: v, ctx: api.WithCaller(v.ctx, frame)}
+	case *defWriter:
+		return &defWriter{logger: v.logger, ctx: api.WithCaller(v.ctx, frame)}
+	}
+	return w
+}
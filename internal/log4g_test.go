@@ -1,7 +1,13 @@
 package internal
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/xtfly/log4g/api"
@@ -108,3 +114,202 @@ func TestLoggerParent(t *testing.T) {
 
 	mo.buf.Truncate(0)
 }
+
+func TestLoggerLevelInherit(t *testing.T) {
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	defer ctx.Close()
+
+	parent := ctx.GetLogger("test3")
+	child := ctx.GetLogger("test3/a/b")
+	assert.Equal(t, parent.Level(), child.Level())
+
+	parent.SetLevel(api.Error)
+	assert.Equal(t, api.Error, child.Level())
+	assert.False(t, child.WarnEnabled())
+	assert.True(t, child.ErrorEnabled())
+
+	child.SetLevel(api.Debug)
+	assert.Equal(t, api.Error, parent.Level())
+	assert.Equal(t, api.Debug, child.Level())
+
+	parent.SetLevel(api.Info)
+	assert.Equal(t, api.Debug, child.Level())
+	assert.Equal(t, api.Info, ctx.GetLogger("test3/a").Level())
+}
+
+func TestLoggerConcurrentSetLevel(t *testing.T) {
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	defer ctx.Close()
+
+	op, _ := NewMemoryOutput(nil)
+	parent := ctx.GetLogger("test4")
+	parent.SetOutputs([]api.Output{op})
+	child := ctx.GetLogger("test4/a")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			parent.SetLevel(api.Level(i%int(api.Off) + 1))
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		child.Info("xxxx")
+	}
+	<-done
+}
+
+func TestLoggerLevelOverride(t *testing.T) {
+	log := GetLogger("test5")
+	log.SetLevel(api.Info)
+	child := GetLogger("test5/a")
+	child.SetLevel(api.Warn)
+
+	gmanager.SetLevel("test5", api.Debug)
+	assert.Equal(t, api.Debug, log.Level())
+	assert.Equal(t, api.Debug, child.Level())
+	assert.Equal(t, api.Debug, GetLogger("test5/a/b").Level())
+	assert.Equal(t, map[string]api.Level{"test5": api.Debug}, gmanager.LevelOverrides())
+
+	// survive the config reloading
+	gfactory.(*factory).notify()
+	assert.Equal(t, api.Debug, child.Level())
+
+	gmanager.SetLevel("test5/*", api.Error)
+	assert.Equal(t, api.Debug, log.Level())
+	assert.Equal(t, api.Error, child.Level())
+	assert.Equal(t, api.Error, GetLogger("test5/a/b").Level())
+
+	gmanager.ResetLevel("test5/*")
+	gmanager.ResetLevel("test5")
+	assert.Equal(t, api.Info, log.Level())
+	assert.Equal(t, api.Warn, child.Level())
+	assert.Empty(t, gmanager.LevelOverrides())
+}
+
+func TestLoggerLevelOverrideKeepRuntime(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "info", OutputNames: []string{"m1"}},
+			{Name: "a", Level: "info", OutputNames: []string{"m1"}},
+		},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}"}},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	op, _ := NewMemoryOutput(nil)
+	log := ctx.GetLogger("a")
+	log.SetLevel(api.Warn)
+	log.SetOutputs([]api.Output{op})
+
+	// the override change does not reload the config of the loggers
+	ctx.Manager().SetLevel("b", api.Debug)
+	ctx.Manager().ResetLevel("b")
+	assert.Equal(t, api.Warn, log.Level())
+	assert.Equal(t, []api.Output{op}, log.(*defLogger).getOutputs().outputs)
+	ctx.Close()
+}
+
+func TestLoggerContext(t *testing.T) {
+	newCfg := func(layout string) *api.Config {
+		return &api.Config{
+			Loggers: []api.CfgLogger{
+				{Name: "root", Level: "info", OutputNames: []string{"m1"}},
+			},
+			Formats: []api.CfgFormat{
+				{"type": "text", "name": "f1", "layout": layout},
+			},
+			Outputs: []api.CfgOutput{
+				{"type": "memory", "name": "m1", "format": "f1"},
+			},
+		}
+	}
+	ctx1, err := NewLoggerContext(newCfg("1>>%{msg}"))
+	assert.NoError(t, err)
+	ctx2, err := NewLoggerContext(newCfg("2>>%{msg}"))
+	assert.NoError(t, err)
+
+	ctx1.GetLogger("a/b").Info("xxxx")
+	ctx2.GetLogger("a/b").Info("xxxx")
+	ctx2.Manager().SetLevel("a", api.Error)
+	ctx2.GetLogger("a/b").Info("yyyy")
+	assert.Equal(t, api.Info, ctx1.GetLogger("a/b").Level())
+
+	mo1 := ctx1.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	mo2 := ctx2.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "1>>xxxx", mo1.String())
+	assert.Equal(t, "2>>xxxx", mo2.String())
+	assert.NotEqual(t, GetLogger("a/b"), ctx1.GetLogger("a/b"))
+
+	ctx1.Close()
+	ctx2.Close()
+
+	_, err = NewLoggerContext(&api.Config{Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}}})
+	assert.Error(t, err)
+}
+
+func TestInitBadConfigFile(t *testing.T) {
+	if os.Getenv("LOG4G_TEST_SUBPROCESS") != "" {
+		// the package initialization has fallen back to the default config
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := gmanager.Config()
+	files := map[string]string{
+		"log4g.toml": "loggers = []",
+		"log4g":      "loggers: []",
+		"log4g.yaml": "loggers: [",
+		"log4g.json": "{\"loggers\": ",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0600))
+		assert.Error(t, Init(api.InitOptions{ConfigFile: file}), name)
+		assert.Equal(t, cfg, gmanager.Config(), name)
+
+		cmd := exec.Command(os.Args[0], "-test.run=^TestInitBadConfigFile$")
+		cmd.Env = append(os.Environ(), "LOG4G_TEST_SUBPROCESS=1", envConfig+"="+file)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, name)
+		assert.Contains(t, string(out), "use the default config", name)
+	}
+}
+
+type flushCountOutput struct {
+	api.Output
+	flushed chan struct{}
+}
+
+func (o *flushCountOutput) Flush() {
+	o.flushed <- struct{}{}
+}
+
+func TestHandleSignals(t *testing.T) {
+	op := &flushCountOutput{flushed: make(chan struct{}, 1)}
+	op.Output, _ = NewMemoryOutput(nil)
+	m := newManager()
+	m.RegisterFormatterCreator(typeText, NewTextFormatter)
+	m.RegisterOutputCreator("flush_count", func(api.CfgOutput) (api.Output, error) { return op, nil })
+	_ = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "info", OutputNames: []string{"o1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}"}},
+		Outputs: []api.CfgOutput{{"type": "flush_count", "name": "o1", "format": "f1"}},
+	})
+	_, _, _ = m.GetLoggerOutputs("root")
+
+	handleSignals(m, nil, []os.Signal{syscall.SIGUSR1})
+	defer handleSignals(gmanager, []os.Signal{os.Interrupt, syscall.SIGTERM}, nil)
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+
+	select {
+	case <-op.flushed:
+	case <-time.After(time.Second):
+		t.Fatal("not flush the output by signal")
+	}
+}
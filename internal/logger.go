@@ -2,7 +2,9 @@ package internal
 
 import (
 	"context"
-	"log"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/xtfly/log4g/api"
@@ -14,33 +16,55 @@ const (
 
 // defLogger is default logger implements interface Logger
 type defLogger struct {
-	name           string       // 日志名称
-	level          api.Level    // 日志开启的级别
-	parent         *defLogger   // 日志的父一级
-	outputs        []api.Output // 日志的Output列表
-	callerSkip     int          // caller skip depth
-	callerInfoFlag int          //
+	name       string       // 日志名称
+	level      api.Level    // 日志配置的级别
+	parent     *defLogger   // 日志的父一级
+	children   []*defLogger // 日志的子一级
+	outputs    []api.Output // 日志配置的Output列表
+	filters    []api.Filter // 日志配置的Filter列表
+	owner      *factory     // 日志所属的factory, 修改级别和Output时需持有其锁
+	override   api.Level    // 日志被Manager覆盖的级别, 继承自父一级
+	effLevel   int32        // 日志生效的级别, atomic访问
+	effOutputs atomic.Value // 日志生效的Output和Filter列表, *loggerOutputs
+	callerSkip int          // caller skip depth
+	callSites  sync.Map     // Once/EveryN/Every限制的调用点状态, key: pc, value: *callSite
 
 	*defWriter
 }
 
-func newLogger(name string) *defLogger {
+// loggerOutputs is the effective outputs and filters of a logger and the caller info flag required by them
+type loggerOutputs struct {
+	outputs        []api.Output
+	filters        []api.Filter
+	callerInfoFlag int
+}
+
+func newLoggerOutputs(outputs []api.Output, filters []api.Filter) *loggerOutputs {
+	lo := &loggerOutputs{outputs: outputs, filters: filters}
+	for _, op := range outputs {
+		if lo.callerInfoFlag < op.CallerInfoFlag() {
+			lo.callerInfoFlag = op.CallerInfoFlag()
+		}
+	}
+	return lo
+}
+
+func newLogger(name string, owner *factory) *defLogger {
 	l := &defLogger{
 		name:       name,
 		level:      api.Uninitialized,
+		owner:      owner,
+		effLevel:   int32(api.Off),
 		callerSkip: callerSkip,
 	}
+	l.effOutputs.Store(newLoggerOutputs(nil, nil))
 	w := &defWriter{logger: l, ctx: context.Background()}
 	l.defWriter = w
 	return l
 }
 
 func (l *defLogger) WithFields(fields ...api.Field) api.Writer {
-	ctx := context.Background()
-	for i := range fields {
-		f := &fields[i]
-		ctx = context.WithValue(ctx, f.Key, f.Value)
-	}
+	ctx := api.WithFields(context.Background(), fields...)
 	w := &defWriter{logger: l, ctx: ctx}
 	return w
 }
@@ -74,22 +98,19 @@ func (l *defLogger) CriticalEnabled() bool {
 }
 
 func (l *defLogger) LevelEnabled(lvl api.Level) bool {
-	return lvl >= l.Level()
+	return lvl >= api.Level(atomic.LoadInt32(&l.effLevel))
 }
 
 func (l *defLogger) SetLevel(lvl api.Level) {
+	l.owner.Lock()
+	defer l.owner.Unlock()
 	l.level = lvl
+	l.refresh()
 }
 
+// Level return the effective level, inherited from the parent if not set
 func (l *defLogger) Level() api.Level {
-	cl := l
-	for cl != nil {
-		if cl.level != api.Uninitialized {
-			return cl.level
-		}
-		cl = cl.parent
-	}
-	return api.Off
+	return api.Level(atomic.LoadInt32(&l.effLevel))
 }
 
 func (l *defLogger) SetCallerSkip(skip int) {
@@ -97,11 +118,47 @@ func (l *defLogger) SetCallerSkip(skip int) {
 }
 
 func (l *defLogger) SetOutputs(outputs []api.Output) {
+	l.owner.Lock()
+	defer l.owner.Unlock()
 	l.outputs = outputs
-	for _, op := range outputs {
-		if l.callerInfoFlag < op.CallerInfoFlag() {
-			l.callerInfoFlag = op.CallerInfoFlag()
+	l.refresh()
+}
+
+// getOutputs return the effective outputs and filters, inherited from the parent if not set
+func (l *defLogger) getOutputs() *loggerOutputs {
+	return l.effOutputs.Load().(*loggerOutputs)
+}
+
+// refresh recompute the effective level, outputs and filters of the logger and all its descendants,
+// the caller must hold the lock of the owner factory.
+func (l *defLogger) refresh() {
+	l.override = l.owner.matchOverride(l.name)
+	if l.override == api.Uninitialized && l.parent != nil {
+		l.override = l.parent.override
+	}
+
+	lvl, ops, fs := l.level, l.outputs, l.filters
+	if l.override != api.Uninitialized {
+		lvl = l.override
+	}
+	if l.parent != nil {
+		if lvl == api.Uninitialized {
+			lvl = l.parent.Level()
 		}
+		if len(ops) == 0 {
+			ops = l.parent.getOutputs().outputs
+		}
+		if len(fs) == 0 {
+			fs = l.parent.getOutputs().filters
+		}
+	} else if lvl == api.Uninitialized {
+		lvl = api.Off
+	}
+
+	atomic.StoreInt32(&l.effLevel, int32(lvl))
+	l.effOutputs.Store(newLoggerOutputs(ops, fs))
+	for _, c := range l.children {
+		c.refresh()
 	}
 }
 
@@ -159,20 +216,109 @@ func (l *defWriter) Critical(msg ...interface{}) {
 }
 
 func (l *defWriter) Printf(lvl api.Level, fmt string, args ...interface{}) {
-	l.write(l.logger.name, l.logger.callerSkip, lvl, fmt, args...)
+	l.write(l.logger.name, l.logger.callerSkip, lvl, nil, fmt, args...)
+}
+
+func (l *defWriter) TraceOnce(msg ...interface{}) {
+	l.printLimited(api.Trace, onceCallSite, msg...)
+}
+
+func (l *defWriter) TraceEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Trace, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) TraceEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Trace, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) DebugOnce(msg ...interface{}) {
+	l.printLimited(api.Debug, onceCallSite, msg...)
+}
+
+func (l *defWriter) DebugEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Debug, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) DebugEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Debug, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) InfoOnce(msg ...interface{}) {
+	l.printLimited(api.Info, onceCallSite, msg...)
 }
 
-func (l *defWriter) write(name string, skip int, lvl api.Level, fmt string, args ...interface{}) {
+func (l *defWriter) InfoEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Info, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) InfoEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Info, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) WarnOnce(msg ...interface{}) {
+	l.printLimited(api.Warn, onceCallSite, msg...)
+}
+
+func (l *defWriter) WarnEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Warn, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) WarnEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Warn, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) ErrorOnce(msg ...interface{}) {
+	l.printLimited(api.Error, onceCallSite, msg...)
+}
+
+func (l *defWriter) ErrorEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Error, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) ErrorEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Error, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) CriticalOnce(msg ...interface{}) {
+	l.printLimited(api.Critical, onceCallSite, msg...)
+}
+
+func (l *defWriter) CriticalEveryN(n int, msg ...interface{}) {
+	l.printLimited(api.Critical, everyNCallSite(n), msg...)
+}
+
+func (l *defWriter) CriticalEvery(d time.Duration, msg ...interface{}) {
+	l.printLimited(api.Critical, everyCallSite(d), msg...)
+}
+
+func (l *defWriter) WithMarker(name string) api.Writer {
+	return &defWriter{logger: l.logger, ctx: api.WithMarkers(l.ctx, name)}
+}
+
+// getCallSite return the state of the call site of the logger identified by pc
+func (l *defLogger) getCallSite(pc uintptr) *callSite {
+	if cs, ok := l.callSites.Load(pc); ok {
+		return cs.(*callSite)
+	}
+	cs, _ := l.callSites.LoadOrStore(pc, &callSite{})
+	return cs.(*callSite)
+}
+
+// printLimited writes the message with lvl if allowed by the call site
+func (l *defWriter) printLimited(lvl api.Level, allow callSiteFunc, msg ...interface{}) {
+	l.write(l.logger.name, l.logger.callerSkip, lvl, allow, "", msg...)
+}
+
+// write a event to the outputs, allow limits the times written by the call site if not nil
+func (l *defWriter) write(name string, skip int, lvl api.Level, allow callSiteFunc, fmt string, args ...interface{}) {
 	if !l.logger.LevelEnabled(lvl) {
 		return
 	}
 
-	if len(l.logger.outputs) == 0 {
-		if l.logger.parent != nil {
-			l.logger.parent.write(name, skip+1, lvl, fmt, args...)
-			return
-		}
-		log.Println("Warnning: not find outputs and parent for logger " + name)
+	lo := l.logger.getOutputs()
+	if len(lo.outputs) == 0 {
+		// not by the standard log package, which may be redirected to the logger
+		_, _ = os.Stderr.WriteString("Warnning: not find outputs and parent for logger " + name + "\n")
 	}
 
 	// create a new logging event
@@ -185,15 +331,29 @@ func (l *defWriter) write(name string, skip int, lvl api.Level, fmt string, args
 		CallDepth: skip,
 		Ctx:       l.ctx,
 	}
+	if fields := l.logger.owner.manager.extractFields(evt.Ctx); len(fields) != 0 {
+		evt.Ctx = api.WithFields(evt.Ctx, fields...)
+	}
+	if filterChain(lo.filters, evt) == api.Deny {
+		return
+	}
+	if allow != nil && !allow(l.logger.getCallSite(getCallerInfo(evt, false).pc)) {
+		return
+	}
 
-	if l.logger.callerInfoFlag == ciFuncFlag {
+	// the outputs routed by markers may require the caller info
+	marked := len(api.MarkersFrom(evt.Ctx)) != 0
+	if lo.callerInfoFlag == ciFuncFlag || marked {
 		getCallerInfo(evt, true)
-	} else if l.logger.callerInfoFlag == ciFileFlag {
+	} else if lo.callerInfoFlag == ciFileFlag {
 		getCallerInfo(evt, false)
 	}
 
 	// dispatch event to all outputs
-	for _, v := range l.logger.outputs {
+	for _, v := range lo.outputs {
 		v.Send(evt)
 	}
+	if marked {
+		l.logger.owner.manager.route(evt, lo.outputs)
+	}
 }
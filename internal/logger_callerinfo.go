@@ -20,6 +20,7 @@ type callerInfo struct {
 	pkg  string
 	fun  string
 	pc   uintptr
+	name string // full function name, got from pc if empty
 }
 
 func getCallerInfo(evt *api.Event, needFun bool) *callerInfo {
@@ -31,17 +32,25 @@ func getCallerInfo(evt *api.Event, needFun bool) *callerInfo {
 
 	if ci == nil {
 		ci = &callerInfo{}
-		var ok bool
-		ci.pc, ci.file, ci.line, ok = runtime.Caller(evt.CallDepth + 1)
-		if !ok {
-			ci.file, ci.line = "???", 0
+		if frame, ok := api.CallerFrom(evt.Ctx); ok {
+			ci.pc, ci.file, ci.line, ci.name = frame.PC, frame.File, frame.Line, frame.Function
+		} else {
+			ci.pc, ci.file, ci.line, ok = runtime.Caller(evt.CallDepth + 1)
+			if !ok {
+				ci.file, ci.line = "???", 0
+			}
 		}
 		evt.Ctx = context.WithValue(evt.Ctx, "__caller_info", ci)
 	}
 
-	if needFun && ci.pkg == "" {
+	if needFun && ci.pkg == "" && ci.name == "" {
 		if f := runtime.FuncForPC(ci.pc); f != nil {
-			fs := f.Name()
+			ci.name = f.Name()
+		}
+	}
+
+	if needFun && ci.pkg == "" {
+		if fs := ci.name; fs != "" {
 			i := strings.LastIndex(fs, "/")
 			j := strings.Index(fs[i+1:], ".")
 			if j < 1 {
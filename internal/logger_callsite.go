@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// callSiteFunc return whether a event is written by the call site with the state cs
+type callSiteFunc func(cs *callSite) bool
+
+// callSite is the state of a call site of a logger limited by the Once, EveryN or Every methods
+type callSite struct {
+	count uint64 // atomic, the times called
+	last  int64  // atomic, the unix nano of the last written
+}
+
+// onceCallSite allow the first time only
+func onceCallSite(cs *callSite) bool {
+	return atomic.AddUint64(&cs.count, 1) == 1
+}
+
+// everyNCallSite return the function allows the first and every n-th time
+func everyNCallSite(n int) callSiteFunc {
+	return func(cs *callSite) bool {
+		if n <= 1 {
+			return true
+		}
+		return (atomic.AddUint64(&cs.count, 1)-1)%uint64(n) == 0
+	}
+}
+
+// everyCallSite return the function allows at most once per d
+func everyCallSite(d time.Duration) callSiteFunc {
+	return func(cs *callSite) bool {
+		now := time.Now().UnixNano()
+		last := atomic.LoadInt64(&cs.last)
+		if last != 0 && now-last < int64(d) {
+			return false
+		}
+		return atomic.CompareAndSwapInt64(&cs.last, last, now)
+	}
+}
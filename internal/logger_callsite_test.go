@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestCallSiteLimited(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "info", OutputNames: []string{"m1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{lvl}|%{msg}|%{shortfile}\n"}},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	l := ctx.GetLogger("callsite")
+	log := l.(api.LimitedWriter)
+
+	for i := 0; i < 5; i++ {
+		log.DebugOnce("disabled") // not consume the once
+		log.InfoOnce("once", i)
+		l.WithFields(api.Field{Key: "k", Value: i}).(api.LimitedWriter).WarnEveryN(2, "every 2 ", i)
+		log.ErrorEvery(time.Hour, "every hour ", i)
+	}
+	// another call site
+	log.InfoOnce("once", 5)
+
+	mo := ctx.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "INF|once0|logger_callsite_test.go\n"+
+		"WRN|every 2 0|logger_callsite_test.go\n"+
+		"ERR|every hour 0|logger_callsite_test.go\n"+
+		"WRN|every 2 2|logger_callsite_test.go\n"+
+		"WRN|every 2 4|logger_callsite_test.go\n"+
+		"INF|once5|logger_callsite_test.go\n", mo.String())
+
+	// the call site is limited per logger
+	ctx2, err := NewLoggerContext(ctx.Manager().Config())
+	assert.NoError(t, err)
+	defer ctx2.Close()
+	mo.buf.Truncate(0)
+	for _, w := range []api.Writer{l, l, ctx.GetLogger("callsite2"), ctx2.GetLogger("callsite")} {
+		w.(api.LimitedWriter).InfoOnce("shared")
+	}
+	assert.Equal(t, "INF|shared|logger_callsite_test.go\nINF|shared|logger_callsite_test.go\n", mo.String())
+	mo2 := ctx2.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "INF|shared|logger_callsite_test.go\n", mo2.String())
+	ctx.Close()
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	defaultErrorInterval   = time.Second
+	defaultStatusQueueSize = 100
+)
+
+// errorLimiter prints the errors of outputs to the writer at most once per interval for each output,
+// the number of the suppressed errors is printed with the next one.
+type errorLimiter struct {
+	sync.Mutex
+	w          io.Writer
+	interval   time.Duration
+	last       map[string]time.Time // key: output name
+	suppressed map[string]int       // key: output name
+}
+
+func newErrorLimiter(w io.Writer, interval time.Duration) *errorLimiter {
+	return &errorLimiter{
+		w:          w,
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// handle implements api.ErrorHandlerFunc
+func (l *errorLimiter) handle(output string, err error) {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	if last, ok := l.last[output]; ok && now.Sub(last) < l.interval {
+		l.suppressed[output]++
+		return
+	}
+	l.last[output] = now
+	if n := l.suppressed[output]; n != 0 {
+		delete(l.suppressed, output)
+		fmt.Fprintf(l.w, "log4g internal error: output[%s]: %s (%d errors suppressed)\n", output, err, n)
+		return
+	}
+	fmt.Fprintf(l.w, "log4g internal error: output[%s]: %s\n", output, err)
+}
+
+// errorHandlers holds the error handler and the status logger of a manager
+type errorHandlers struct {
+	handler atomic.Value // api.ErrorHandlerFunc
+	status  atomic.Value // *statusLogger
+
+	sync.Mutex // guards setting the status logger
+}
+
+// statusLogger records the errors of outputs by the logger in its goroutine, so the outputs written by
+// the logger are not re-entered by the goroutine reporting the error, which may hold their locks.
+type statusLogger struct {
+	l       api.Logger
+	records chan statusRecord
+	done    chan struct{}
+	dropped uint64 // atomic, the records dropped as the queue is full
+}
+
+type statusRecord struct {
+	output  string
+	err     error
+	flushed chan struct{} // not nil if it is the request of flush, closed when the records before it recorded
+}
+
+type statusRecordKey struct{}
+
+// statusRecordError is the error of writing the status records only, it is not recorded again,
+// otherwise a broken output written by the status logger records its errors endlessly.
+type statusRecordError struct {
+	error
+}
+
+// isStatusRecord return whether the event is written by the status logger
+func isStatusRecord(e *api.Event) bool {
+	return e.Ctx != nil && e.Ctx.Value(statusRecordKey{}) != nil
+}
+
+// markStatusError return the error marked as the one of writing the status records only if status is true
+func markStatusError(err error, status bool) error {
+	if status {
+		return statusRecordError{err}
+	}
+	return err
+}
+
+func (h *errorHandlers) init() {
+	h.handler.Store(api.ErrorHandlerFunc(newErrorLimiter(os.Stderr, defaultErrorInterval).handle))
+	h.status.Store((*statusLogger)(nil))
+}
+
+func (h *errorHandlers) setHandler(f api.ErrorHandlerFunc) {
+	if f == nil {
+		f = newErrorLimiter(os.Stderr, defaultErrorInterval).handle
+	}
+	h.handler.Store(f)
+}
+
+// setStatusLogger replace the status logger, the records queued for the old one are dropped
+func (h *errorHandlers) setStatusLogger(l api.Logger) {
+	h.Lock()
+	defer h.Unlock()
+	if old := h.status.Load().(*statusLogger); old != nil {
+		close(old.done)
+	}
+	if l == nil {
+		h.status.Store((*statusLogger)(nil))
+		return
+	}
+	sl := &statusLogger{
+		l:       l,
+		records: make(chan statusRecord, defaultStatusQueueSize),
+		done:    make(chan struct{}),
+	}
+	h.status.Store(sl)
+	go sl.loop()
+}
+
+// handle the error of the output by the error handler and queue it to be recorded by the status logger,
+// the error is dropped by the status logger if its queue is full
+func (h *errorHandlers) handle(output string, err error) {
+	se, status := err.(statusRecordError)
+	if status {
+		err = se.error
+	}
+	h.handler.Load().(api.ErrorHandlerFunc)(output, err)
+
+	sl := h.status.Load().(*statusLogger)
+	if sl == nil || status {
+		return
+	}
+	select {
+	case sl.records <- statusRecord{output: output, err: err}:
+	default:
+		atomic.AddUint64(&sl.dropped, 1)
+	}
+}
+
+// flush wait until the queued records are recorded
+func (h *errorHandlers) flush() {
+	sl := h.status.Load().(*statusLogger)
+	if sl == nil {
+		return
+	}
+	req := statusRecord{flushed: make(chan struct{})}
+	select {
+	case sl.records <- req:
+	case <-sl.done:
+		return
+	}
+	select {
+	case <-req.flushed:
+	case <-sl.done:
+	}
+}
+
+func (sl *statusLogger) loop() {
+	ctx := context.WithValue(context.Background(), statusRecordKey{}, true)
+	w := sl.l.WithCtx(ctx)
+	for {
+		select {
+		case <-sl.done:
+			return
+		case r := <-sl.records:
+			if r.flushed != nil {
+				close(r.flushed)
+			} else if n := atomic.SwapUint64(&sl.dropped, 0); n != 0 {
+				w.Errorf("output[%s]: %s (%d status records dropped)", r.output, r.err, n)
+			} else {
+				w.Errorf("output[%s]: %s", r.output, r.err)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestErrorLimiter(t *testing.T) {
+	var buf bytes.Buffer
+	l := newErrorLimiter(&buf, 50*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		l.handle("o1", fmt.Errorf("e%d", i))
+	}
+	l.handle("o2", errors.New("e"))
+	time.Sleep(60 * time.Millisecond)
+	l.handle("o1", errors.New("e3"))
+	assert.Equal(t, "log4g internal error: output[o1]: e0\n"+
+		"log4g internal error: output[o2]: e\n"+
+		"log4g internal error: output[o1]: e3 (2 errors suppressed)\n", buf.String())
+}
+
+func TestErrorHandler(t *testing.T) {
+	broken := &switchWriter{broken: 1}
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	m := ctx.Manager()
+	m.RegisterOutputCreator("switch", func(cfg api.CfgOutput) (api.Output, error) {
+		if cfg["async"] == "true" {
+			return newAsyncOutput(broken, api.All, 10, 10, nil), nil
+		}
+		return newBaseOutput(broken, api.All), nil
+	})
+	err = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "all", OutputNames: []string{"b1", "b2"}},
+			{Name: "log4g/status", Level: "all", OutputNames: []string{"m1", "b1"}},
+		},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{module}|%{msg}\n"}},
+		Outputs: []api.CfgOutput{
+			{"type": "switch", "name": "b1", "format": "f1"},
+			{"type": "switch", "name": "b2", "format": "f1", "async": "true"},
+			{"type": "memory", "name": "m1", "format": "f1"},
+		},
+	})
+	assert.NoError(t, err)
+
+	var lock sync.Mutex
+	var handled []string
+	m.SetErrorHandler(func(output string, err error) {
+		lock.Lock()
+		handled = append(handled, output+":"+err.Error())
+		lock.Unlock()
+	})
+	m.SetStatusLogger(ctx.GetLogger("log4g/status"))
+
+	ctx.GetLogger("a").Info("hello")
+	m.Flush()
+	// the errors of b1 when writing the status records are handled but not recorded again,
+	// the status records are written by the goroutine of the status logger
+	lock.Lock()
+	assert.ElementsMatch(t, []string{"b1:broken", "b1:broken", "b2:broken", "b1:broken"}, handled)
+	lock.Unlock()
+	mo := m.(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "log4g/status|output[b1]: broken\nlog4g/status|output[b2]: broken\n", mo.String())
+
+	stats := m.Outputs()
+	assert.Equal(t, uint64(3), stats[0].Stats.Errors)
+	assert.Equal(t, uint64(1), stats[1].Stats.Errors)
+
+	// the default handler is restored, and the status logger is disabled
+	m.SetErrorHandler(nil)
+	m.SetStatusLogger(nil)
+	atomic.StoreInt32(&broken.broken, 0)
+	ctx.GetLogger("a").Info("world")
+	m.Flush()
+	assert.True(t, strings.HasSuffix(broken.String(), "a|world\na|world\n"))
+	assert.Len(t, handled, 4)
+	ctx.Close()
+}
+
+func TestStatusLoggerAsync(t *testing.T) {
+	broken := &switchWriter{broken: 1}
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	m := ctx.Manager()
+	m.RegisterOutputCreator("switch", func(cfg api.CfgOutput) (api.Output, error) {
+		return newAsyncOutput(broken, api.All, 1, 1, nil), nil
+	})
+	err = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "all", OutputNames: []string{"b1"}},
+			{Name: "log4g/status", Level: "all", OutputNames: []string{"b1", "m1"}},
+		},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+		Outputs: []api.CfgOutput{
+			{"type": "switch", "name": "b1", "format": "f1"},
+			{"type": "memory", "name": "m1", "format": "f1"},
+		},
+	})
+	assert.NoError(t, err)
+	var handled int32
+	m.SetErrorHandler(func(output string, err error) { atomic.AddInt32(&handled, 1) })
+	// the status logger records the errors of the async output to itself, the loop of the output
+	// is not blocked by sending the records back to its full queue
+	m.SetStatusLogger(ctx.GetLogger("log4g/status"))
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 50; i++ {
+			ctx.GetLogger("a").Info("hello")
+		}
+		m.Flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock when recording the errors")
+	}
+
+	// the errors of writing the status records only are not recorded again
+	mo := m.(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, 50, strings.Count(mo.String(), "output[b1]: broken"))
+	assert.True(t, atomic.LoadInt32(&handled) > 50)
+	ctx.Close()
+}
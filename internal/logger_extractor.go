@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	extractorTraceparent = "traceparent"
+
+	fieldTraceID = "trace_id"
+	fieldSpanID  = "span_id"
+)
+
+// extractTraceparent return the trace_id and span_id fields parsed from the W3C traceparent
+// carried by ctx, the format is 'version-traceid-spanid-flags', like
+// '00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'
+func extractTraceparent(ctx context.Context) []api.Field {
+	tp := api.TraceparentFrom(ctx)
+	if tp == "" {
+		return nil
+	}
+
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return nil
+	}
+	traceID, spanID := parts[1], parts[2]
+	if !isValidTraceID(traceID, 32) || !isValidTraceID(spanID, 16) {
+		return nil
+	}
+	return []api.Field{{Key: fieldTraceID, Value: traceID}, {Key: fieldSpanID, Value: spanID}}
+}
+
+// isValidTraceID reports whether the id is lowercase hex with the length and not all zeros
+func isValidTraceID(id string, n int) bool {
+	if len(id) != n || strings.ToLower(id) != id || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
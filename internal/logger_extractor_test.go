@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestExtractTraceparent(t *testing.T) {
+	ctx := api.WithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Equal(t, []api.Field{
+		{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Key: "span_id", Value: "00f067aa0ba902b7"},
+	}, extractTraceparent(ctx))
+
+	for _, tp := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902bx-01"} {
+		assert.Nil(t, extractTraceparent(api.WithTraceparent(context.Background(), tp)), tp)
+	}
+}
+
+func TestContextExtractor(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"m1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{trace_id}|%{span_id}|%{user}|%{msg}\n"}},
+		Outputs: []api.CfgOutput{{"type": "memory", "name": "m1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	ctx.Manager().RegisterContextExtractor("user", func(c context.Context) []api.Field {
+		if u, ok := c.Value(struct{}{}).(string); ok {
+			return []api.Field{{Key: "user", Value: u}}
+		}
+		return nil
+	})
+
+	log := ctx.GetLogger("extractor")
+	c := api.WithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	log.WithCtx(context.WithValue(c, struct{}{}, "bob")).Info("hello")
+	log.Info("world")
+
+	ctx.Manager().RegisterContextExtractor("traceparent", nil)
+	log.WithCtx(c).Info("none")
+
+	mo := ctx.Manager().(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736|00f067aa0ba902b7|bob|hello\n||<nil>|world\n||<nil>|none\n", mo.String())
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"path"
 	"sync"
 
 	"github.com/xtfly/log4g/api"
@@ -15,9 +16,10 @@ const (
 // factory implements Factory interface.
 type factory struct {
 	sync.Mutex
-	manager api.Manager
-	root    *defLogger
-	loggers map[string]*defLogger
+	manager   *defManager
+	root      *defLogger
+	loggers   map[string]*defLogger
+	overrides map[string]api.Level // level overrides of the manager, key: logger name pattern
 }
 
 func (f *factory) GetLogger(name string) api.Logger {
@@ -31,12 +33,6 @@ func (f *factory) GetLogger(name string) api.Logger {
 	l, ok := f.loggers[name]
 	if !ok {
 		l = f.createLogger(name, f.getParent(name))
-		if ops, lvl, err := f.manager.GetLoggerOutputs(name); err != nil {
-			//log.Println("WARN: ", err)
-		} else {
-			l.SetLevel(lvl)
-			l.SetOutputs(ops)
-		}
 	}
 
 	return l
@@ -61,51 +57,120 @@ func (f *factory) getParent(name string) *defLogger {
 func (f *factory) createLogger(name string, parent *defLogger) *defLogger {
 	l, ok := f.loggers[name]
 	if !ok {
-		l = newLogger(name)
+		l = newLogger(name, f)
 		l.parent = parent
+		parent.children = append(parent.children, l)
+		f.loadConfig(l)
+		l.refresh()
 		f.loggers[name] = l
 	}
 	return l
 }
 
+// loadConfig set the configured level, outputs and filters of the logger if it is configured
+func (f *factory) loadConfig(l *defLogger) {
+	if ops, lvl, err := f.manager.GetLoggerOutputs(l.name); err != nil {
+		//log.Println("WARN: ", err)
+	} else {
+		l.level = lvl
+		l.outputs = ops
+	}
+	if fs, err := f.manager.GetLoggerFilters(l.name); err == nil {
+		l.filters = fs
+	}
+}
+
 func (f *factory) getRootLogger() *defLogger {
 	if f.root != nil {
 		return f.root
 	}
 
-	f.root = newLogger(rootLoggerName)
+	f.root = newLogger(rootLoggerName, f)
 	if ops, lvl, err := f.manager.GetLoggerOutputs(rootLoggerName); err != nil {
-		f.root.SetLevel(api.Debug)
+		f.root.level = api.Debug
 		console, _ := NewConsoleOutput(nil)
-		f.root.SetOutputs([]api.Output{console})
+		f.root.outputs = []api.Output{console}
 	} else {
-		f.root.SetLevel(lvl)
-		f.root.SetOutputs(ops)
+		f.root.level = lvl
+		f.root.outputs = ops
 	}
+	if fs, err := f.manager.GetLoggerFilters(rootLoggerName); err == nil {
+		f.root.filters = fs
+	}
+	f.root.refresh()
 
 	f.loggers[rootLoggerName] = f.root
 	return f.root
 }
 
+// matchOverride return the level override matched the logger name,
+// the exact name takes precedence over the longest matched pattern.
+func (f *factory) matchOverride(name string) api.Level {
+	if lvl, ok := f.overrides[name]; ok {
+		return lvl
+	}
+	if name == rootLoggerName {
+		if lvl, ok := f.overrides[""]; ok {
+			return lvl
+		}
+	}
+
+	lvl, plen := api.Uninitialized, -1
+	for pattern, v := range f.overrides {
+		if ok, _ := path.Match(pattern, name); ok && len(pattern) > plen {
+			lvl, plen = v, len(pattern)
+		}
+	}
+	return lvl
+}
+
+// loggerInfos return the snapshots of all created loggers
+func (f *factory) loggerInfos() []api.LoggerInfo {
+	f.Lock()
+	defer f.Unlock()
+	infos := make([]api.LoggerInfo, 0, len(f.loggers))
+	for _, l := range f.loggers {
+		infos = append(infos, api.LoggerInfo{
+			Name:           l.name,
+			Level:          l.level,
+			EffectiveLevel: l.Level(),
+			Outputs:        f.manager.outputNames(l.getOutputs().outputs),
+		})
+	}
+	return infos
+}
+
 func (f *factory) notify() {
 	f.Lock()
 	defer f.Unlock()
+	f.overrides = f.manager.LevelOverrides()
 	for _, k := range f.loggers {
-		if ops, lvl, err := f.manager.GetLoggerOutputs(k.name); err != nil {
-			//log.Println("WARN: ", err)
-		} else {
-			k.SetLevel(lvl)
-			k.SetOutputs(ops)
-		}
+		f.loadConfig(k)
+	}
+	if f.root != nil {
+		f.root.refresh()
+	}
+}
+
+// notifyOverrides recompute the effective levels with the level overrides only,
+// the levels and outputs set at runtime are kept
+func (f *factory) notifyOverrides() {
+	f.Lock()
+	defer f.Unlock()
+	f.overrides = f.manager.LevelOverrides()
+	if f.root != nil {
+		f.root.refresh()
 	}
 }
 
 // newFactory return a instance of Factory
 func newFactory(manager api.Manager) api.Factory {
+	dm := manager.(*defManager)
 	factory := &factory{
-		loggers: make(map[string]*defLogger),
-		manager: manager,
+		loggers:   make(map[string]*defLogger),
+		manager:   dm,
+		overrides: dm.LevelOverrides(),
 	}
-	(manager.(*defManager)).addConfigNotify(factory)
+	dm.addConfigNotify(factory)
 	return factory
 }
@@ -1,41 +1,78 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"encoding/json"
 
 	"path"
+	"sort"
 
 	"github.com/xtfly/log4g/api"
 )
 
 type configNotification interface {
 	notify()
+	notifyOverrides()
 }
 
 type defManager struct {
 	sync.RWMutex
 	formatterCreators map[string]api.FormatterFuncCreator // key: type
 	outputCreators    map[string]api.OutputFuncCreator    // key: type
+	filterCreators    map[string]api.FilterFuncCreator    // key: type
 	formats           map[string]api.Formatter            // key: name
+	filters           map[string]api.Filter               // key: name
 	outputs           map[string]api.Output               // key: name
 	config            *api.Config
+	overrides         map[string]api.Level // key: logger name pattern
+	extractors        atomic.Value         // []namedExtractor, read by every event without lock
+	routes            atomic.Value         // []markerRoute, read by every event with markers without lock
+	errors            errorHandlers
 	cfgNotifications  []configNotification
 }
 
+// markerRoute routes the events with one of the markers to the output
+type markerRoute struct {
+	markers []string
+	output  api.Output
+}
+
+func (r markerRoute) match(evt *api.Event) bool {
+	for _, name := range r.markers {
+		if api.HasMarker(evt.Ctx, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type namedExtractor struct {
+	name string
+	e    api.ContextExtractorFunc
+}
+
 func newManager() api.Manager {
-	return &defManager{
+	m := &defManager{
 		formatterCreators: make(map[string]api.FormatterFuncCreator),
 		outputCreators:    make(map[string]api.OutputFuncCreator),
+		filterCreators:    make(map[string]api.FilterFuncCreator),
 		formats:           make(map[string]api.Formatter),
+		filters:           make(map[string]api.Filter),
 		outputs:           make(map[string]api.Output),
 		config:            &api.Config{},
+		overrides:         make(map[string]api.Level),
 	}
+	m.extractors.Store([]namedExtractor{{name: extractorTraceparent, e: extractTraceparent}})
+	m.routes.Store([]markerRoute(nil))
+	m.errors.init()
+	return m
 }
 
 func (m *defManager) RegisterFormatterCreator(stype string, f api.FormatterFuncCreator) {
@@ -50,6 +87,59 @@ func (m *defManager) RegisterOutputCreator(stype string, o api.OutputFuncCreator
 	m.Unlock()
 }
 
+func (m *defManager) SetErrorHandler(h api.ErrorHandlerFunc) {
+	m.errors.setHandler(h)
+}
+
+func (m *defManager) SetStatusLogger(l api.Logger) {
+	m.errors.setStatusLogger(l)
+}
+
+// outputCreator return the registered output creator of the type, the caller must hold the lock,
+// it is called by the routing output creator which is called with the lock held.
+func (m *defManager) outputCreator(stype string) (api.OutputFuncCreator, bool) {
+	o, ok := m.outputCreators[stype]
+	return o, ok
+}
+
+// targetOutput return the output by name as the target of a failover or ring output, the caller must hold the lock.
+// The failover and ring outputs can not be a target to avoid the cycles.
+func (m *defManager) targetOutput(name string) (api.Output, error) {
+	if c := m.config.GetCfgOutput(name); c != nil && (c.Type() == typeFailover || c.Type() == typeRing) {
+		return nil, fmt.Errorf("%s output[%s] can not be a target", c.Type(), name)
+	}
+	return m.getOutput(name)
+}
+
+func (m *defManager) RegisterFilterCreator(stype string, f api.FilterFuncCreator) {
+	m.Lock()
+	m.filterCreators[stype] = f
+	m.Unlock()
+}
+
+func (m *defManager) RegisterContextExtractor(name string, e api.ContextExtractorFunc) {
+	m.Lock()
+	defer m.Unlock()
+	var extractors []namedExtractor
+	for _, ne := range m.extractors.Load().([]namedExtractor) {
+		if ne.name != name {
+			extractors = append(extractors, ne)
+		}
+	}
+	if e != nil {
+		extractors = append(extractors, namedExtractor{name: name, e: e})
+	}
+	m.extractors.Store(extractors)
+}
+
+// extractFields return the fields extracted from ctx by all registered extractors
+func (m *defManager) extractFields(ctx context.Context) (fields []api.Field) {
+	for _, ne := range m.extractors.Load().([]namedExtractor) {
+		fields = append(fields, ne.e(ctx)...)
+	}
+	return
+}
+
 func (m *defManager) GetLoggerOutputs(name string) (ops []api.Output, lvl api.Level, err error) {
 	lvl = api.Uninitialized
 	m.Lock()
@@ -61,43 +151,140 @@ func (m *defManager) GetLoggerOutputs(name string) (ops []api.Output, lvl api.Le
 	lvl = api.LevelFrom(lc.Level)
 
 	for _, opid := range lc.OutputNames {
-		opcfg := m.config.GetCfgOutput(opid)
-		if opcfg == nil {
-			return nil, lvl, fmt.Errorf("not find output.name[%s] config", opid)
+		op, err := m.getOutput(opid)
+		if err != nil {
+			return nil, lvl, err
 		}
+		ops = append(ops, op)
+	}
+	return
+}
 
-		fmtcfg := m.config.GetCfgFormat(opcfg.FormatName())
-		if fmtcfg == nil {
-			return nil, lvl, fmt.Errorf("not find format.name[%s] config", opcfg.FormatName())
-		}
+// getOutput return the output by name, create it if not created, the caller must hold the lock
+func (m *defManager) getOutput(name string) (op api.Output, err error) {
+	opcfg := m.config.GetCfgOutput(name)
+	if opcfg == nil {
+		return nil, fmt.Errorf("not find output.name[%s] config", name)
+	}
 
-		fmtcreator, ok := m.formatterCreators[fmtcfg.Type()]
-		if !ok {
-			return nil, lvl, fmt.Errorf("not find registered format.type[%s] creator", fmtcfg.Type())
+	fmtcfg := m.config.GetCfgFormat(opcfg.FormatName())
+	if fmtcfg == nil {
+		return nil, fmt.Errorf("not find format.name[%s] config", opcfg.FormatName())
+	}
+
+	fmtcreator, ok := m.formatterCreators[fmtcfg.Type()]
+	if !ok {
+		return nil, fmt.Errorf("not find registered format.type[%s] creator", fmtcfg.Type())
+	}
+
+	fmtt, ok := m.formats[fmtcfg.Name()]
+	if !ok {
+		if fmtt, err = fmtcreator(fmtcfg); err != nil {
+			return
 		}
+		m.formats[fmtcfg.Name()] = fmtt
+	}
 
-		fmtt, ok := m.formats[fmtcfg.Name()]
-		if !ok {
-			if fmtt, err = fmtcreator(fmtcfg); err != nil {
-				return
+	opcreator, ok := m.outputCreators[opcfg.Type()]
+	if !ok {
+		return nil, fmt.Errorf("not find registered output.type[%s] creator", opcfg.Type())
+	}
+
+	op, ok = m.outputs[opcfg.Name()]
+	if !ok {
+		if op, err = opcreator(opcfg); err != nil {
+			return
+		}
+		op.SetFormatter(fmtt)
+		if eo, ok := op.(errorReportOutput); ok {
+			opname := opcfg.Name()
+			eo.SetErrorHandler(func(err error) { m.errors.handle(opname, err) })
+		}
+		if names := opcfg.FilterNames(); len(names) != 0 {
+			fo, ok := op.(api.FilterOutput)
+			if !ok {
+				op.Close()
+				return nil, fmt.Errorf("output.type[%s] not support filters", opcfg.Type())
 			}
-			m.formats[fmtcfg.Name()] = fmtt
+			fs, err := m.getFilters(names)
+			if err != nil {
+				op.Close()
+				return nil, err
+			}
+			fo.SetFilters(fs)
 		}
+		m.outputs[opcfg.Name()] = op
+	}
+	return
+}
 
-		opcreator, ok := m.outputCreators[opcfg.Type()]
-		if !ok {
-			return nil, api.Uninitialized, fmt.Errorf("not find registered output.type[%s] creator", opcfg.Type())
+// loadRoutes create the outputs configured with 'markers' and return the routes of the events
+// with the markers to them, the caller must hold the lock
+func (m *defManager) loadRoutes() ([]markerRoute, error) {
+	var routes []markerRoute
+	for _, c := range m.config.Outputs {
+		names := c.MarkerNames()
+		if len(names) == 0 {
+			continue
+		}
+		op, err := m.getOutput(c.Name())
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, markerRoute{markers: names, output: op})
+	}
+	return routes, nil
+}
+
+// route send the event to the outputs routed by the markers of the event, except the sent ones,
+// the caller info of the event must be got before
+func (m *defManager) route(evt *api.Event, sent []api.Output) {
+	for _, r := range m.routes.Load().([]markerRoute) {
+		if !r.match(evt) || containsOutput(sent, r.output) {
+			continue
+		}
+		r.output.Send(evt)
+	}
+}
+
+func containsOutput(ops []api.Output, op api.Output) bool {
+	for _, v := range ops {
+		if v == op {
+			return true
 		}
+	}
+	return false
+}
 
-		op, ok := m.outputs[opcfg.Name()]
+func (m *defManager) GetLoggerFilters(name string) (fs []api.Filter, err error) {
+	m.Lock()
+	defer m.Unlock()
+	lc := m.config.GetCfgLogger(name)
+	if lc == nil {
+		return nil, fmt.Errorf("not find logger.name[%s] config", name)
+	}
+	return m.getFilters(lc.FilterNames)
+}
+
+// getFilters return the filters by names, create them if not created, the caller must hold the lock
+func (m *defManager) getFilters(names []string) (fs []api.Filter, err error) {
+	for _, name := range names {
+		ft, ok := m.filters[name]
 		if !ok {
-			if op, err = opcreator(opcfg); err != nil {
-				return
+			ftcfg := m.config.GetCfgFilter(name)
+			if ftcfg == nil {
+				return nil, fmt.Errorf("not find filter.name[%s] config", name)
+			}
+			ftcreator, ok := m.filterCreators[ftcfg.Type()]
+			if !ok {
+				return nil, fmt.Errorf("not find registered filter.type[%s] creator", ftcfg.Type())
 			}
-			op.SetFormatter(fmtt)
-			m.outputs[opcfg.Name()] = op
+			if ft, err = ftcreator(ftcfg); err != nil {
+				return nil, err
+			}
+			m.filters[name] = ft
 		}
-		ops = append(ops, op)
+		fs = append(fs, ft)
 	}
 	return
 }
@@ -108,6 +295,9 @@ func (m *defManager) LoadConfigFile(file string) error {
 		return err
 	}
 	ext := path.Ext(file)
+	if ext == "" {
+		return fmt.Errorf("not support config file %s without extension", file)
+	}
 	return m.LoadConfig(bs, ext[1:])
 }
 
@@ -121,6 +311,9 @@ func (m *defManager) LoadConfig(bs []byte, ext string) (err error) {
 	} else if ext == "json" {
 		err = json.Unmarshal(bs, cfg)
 	}
+	if err != nil {
+		return err
+	}
 
 	return m.setConfig(cfg)
 }
@@ -131,12 +324,43 @@ func (m *defManager) setConfig(cfg *api.Config) error {
 	}
 
 	m.Lock()
+	oldConfig, oldFilters := m.config, m.filters
+	existing := make(map[string]bool, len(m.outputs))
+	for name := range m.outputs {
+		existing[name] = true
+	}
 	m.config = cfg
+	// the filters are created again by the new config
+	m.filters = make(map[string]api.Filter)
+	routes, err := m.loadRoutes()
+	if err != nil {
+		// keep the old config, the outputs created by the new one are closed
+		m.config, m.filters = oldConfig, oldFilters
+		for name, op := range m.outputs {
+			if !existing[name] {
+				op.Close()
+				delete(m.outputs, name)
+			}
+		}
+		m.Unlock()
+		return err
+	}
+	m.routes.Store(routes)
 	m.Unlock()
+	m.notifyAll()
+	return nil
+}
+
+func (m *defManager) notifyAll() {
 	for _, cn := range m.cfgNotifications {
 		cn.notify()
 	}
-	return nil
+}
+
+func (m *defManager) notifyOverrides() {
+	for _, cn := range m.cfgNotifications {
+		cn.notifyOverrides()
+	}
 }
 
 func (m *defManager) validateConfig(cfg *api.Config) (err error) {
@@ -151,6 +375,15 @@ func (m *defManager) validateConfig(cfg *api.Config) (err error) {
 		formats[f.Name()] = f
 	}
 
+	filters := make(map[string]api.CfgFilter)
+	for _, f := range cfg.Filters {
+		if _, ok := filters[f.Name()]; ok {
+			err = fmt.Errorf("duplication filter[%s] config", f.Name())
+			return
+		}
+		filters[f.Name()] = f
+	}
+
 	outputs := make(map[string]api.CfgOutput)
 	for _, o := range cfg.Outputs {
 		if _, ok := outputs[o.Name()]; ok {
@@ -161,6 +394,12 @@ func (m *defManager) validateConfig(cfg *api.Config) (err error) {
 			err = fmt.Errorf("not found format[%s] for output[%s] ", o.FormatName(), o.Name())
 			return
 		}
+		for _, filterName := range o.FilterNames() {
+			if _, ok := filters[filterName]; !ok {
+				err = fmt.Errorf("not found filter[%s] for output[%s] ", filterName, o.Name())
+				return
+			}
+		}
 		outputs[o.Name()] = o
 	}
 
@@ -177,6 +416,12 @@ func (m *defManager) validateConfig(cfg *api.Config) (err error) {
 				return
 			}
 		}
+		for _, filterName := range l.FilterNames {
+			if _, ok := filters[filterName]; !ok {
+				err = fmt.Errorf("not found filter[%s] for logger[%s] ", filterName, l.Name)
+				return
+			}
+		}
 	}
 
 	return
@@ -186,7 +431,122 @@ func (m *defManager) SetConfig(cfg *api.Config) error {
 	return m.setConfig(cfg)
 }
 
+func (m *defManager) SetLevel(pattern string, lvl api.Level) {
+	m.Lock()
+	m.overrides[pattern] = lvl
+	m.Unlock()
+	m.notifyOverrides()
+}
+
+func (m *defManager) ResetLevel(pattern string) {
+	m.Lock()
+	_, ok := m.overrides[pattern]
+	delete(m.overrides, pattern)
+	m.Unlock()
+	if ok {
+		m.notifyOverrides()
+	}
+}
+
+func (m *defManager) LevelOverrides() map[string]api.Level {
+	m.RLock()
+	defer m.RUnlock()
+	ret := make(map[string]api.Level, len(m.overrides))
+	for k, v := range m.overrides {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (m *defManager) Loggers() []api.LoggerInfo {
+	var infos []api.LoggerInfo
+	for _, cn := range m.cfgNotifications {
+		if f, ok := cn.(*factory); ok {
+			infos = append(infos, f.loggerInfos()...)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (m *defManager) Outputs() []api.OutputInfo {
+	m.RLock()
+	defer m.RUnlock()
+	infos := make([]api.OutputInfo, 0, len(m.config.Outputs))
+	for _, c := range m.config.Outputs {
+		info := api.OutputInfo{Name: c.Name(), Type: c.Type(), Config: c.Clone()}
+		if op, ok := m.outputs[c.Name()]; ok {
+			info.Created = true
+			if so, ok := op.(api.StatsOutput); ok {
+				stats := so.Stats()
+				info.Stats = &stats
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (m *defManager) Formats() []api.FormatInfo {
+	m.RLock()
+	defer m.RUnlock()
+	infos := make([]api.FormatInfo, 0, len(m.config.Formats))
+	for _, c := range m.config.Formats {
+		_, created := m.formats[c.Name()]
+		infos = append(infos, api.FormatInfo{Name: c.Name(), Type: c.Type(), Config: c.Clone(), Created: created})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (m *defManager) Config() *api.Config {
+	m.RLock()
+	defer m.RUnlock()
+	return m.config.Clone()
+}
+
+// outputNames return the configured names of outputs, the type is used if not found
+func (m *defManager) outputNames(ops []api.Output) []string {
+	m.RLock()
+	defer m.RUnlock()
+	names := make([]string, 0, len(ops))
+	for _, op := range ops {
+		name := fmt.Sprintf("%T", op)
+		for k, v := range m.outputs {
+			if v == op {
+				name = k
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (m *defManager) Flush() {
+	m.RLock()
+	var fos []api.FlushOutput
+	for _, v := range m.outputs {
+		if fo, ok := v.(api.FlushOutput); ok {
+			fos = append(fos, fo)
+		}
+	}
+	m.RUnlock()
+
+	for _, fo := range fos {
+		fo.Flush()
+	}
+	// the status records of the errors of flushing are written and flushed too
+	m.errors.flush()
+	for _, fo := range fos {
+		fo.Flush()
+	}
+}
+
 func (m *defManager) Close() {
+	// stop recording the errors of the outputs closed
+	m.errors.setStatusLogger(nil)
 	m.Lock()
 	for _, v := range m.outputs {
 		v.Close()
@@ -82,3 +82,52 @@ func TestManager(t *testing.T) {
 	assert.Equal(t, api.Error, lvl)
 	assert.Equal(t, 2, len(ops))
 }
+
+func TestManagerIntrospection(t *testing.T) {
+	m := newManager()
+	m.RegisterFormatterCreator(typeText, NewTextFormatter)
+	m.RegisterOutputCreator(typeMemory, NewMemoryOutput)
+	f := newFactory(m)
+
+	cfg := &api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "info", OutputNames: []string{"m1"}},
+		},
+		Formats: []api.CfgFormat{
+			{"type": "text", "name": "f1", "layout": "%{msg}"},
+			{"type": "text", "name": "f2", "layout": "%{msg}"},
+		},
+		Outputs: []api.CfgOutput{
+			{"type": "memory", "name": "m1", "format": "f1"},
+			{"type": "memory", "name": "m2", "format": "f2"},
+		},
+	}
+	assert.NoError(t, m.SetConfig(cfg))
+
+	log := f.GetLogger("a/b")
+	log.Info("hello")
+	log.Debug("hello")
+
+	loggers := m.Loggers()
+	assert.Equal(t, 3, len(loggers))
+	assert.Equal(t, api.LoggerInfo{Name: "a/b", Level: api.Uninitialized, EffectiveLevel: api.Info,
+		Outputs: []string{"m1"}}, loggers[1])
+
+	outputs := m.Outputs()
+	assert.Equal(t, 2, len(outputs))
+	assert.True(t, outputs[0].Created)
+	assert.Equal(t, &api.OutputStats{Events: 1, Bytes: 5}, outputs[0].Stats)
+	assert.False(t, outputs[1].Created)
+	assert.Nil(t, outputs[1].Stats)
+
+	formats := m.Formats()
+	assert.Equal(t, 2, len(formats))
+	assert.Equal(t, "%{msg}", formats[0].Config["layout"])
+	assert.True(t, formats[0].Created)
+	assert.False(t, formats[1].Created)
+
+	c := m.Config()
+	assert.Equal(t, cfg, c)
+	c.Outputs[0]["type"] = "console"
+	assert.Equal(t, "memory", m.Config().Outputs[0].Type())
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestMarkerRouting(t *testing.T) {
+	api.GetMarker("LOGIN").AddParents(api.GetMarker("AUDIT"))
+
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{
+			{Name: "root", Level: "info", OutputNames: []string{"m1"}},
+			{Name: "a", Level: "info", OutputNames: []string{"m2"}},
+		},
+		Formats: []api.CfgFormat{
+			{"type": "text", "name": "f1", "layout": "%{module}|%{marker}|%{msg}\n"},
+			{"type": "text", "name": "f2", "layout": "%{module}|%{marker}|%{msg}|%{shortfunc}\n"},
+		},
+		Outputs: []api.CfgOutput{
+			{"type": "memory", "name": "m1", "format": "f1"},
+			{"type": "memory", "name": "m2", "format": "f1"},
+			{"type": "memory", "name": "audit", "format": "f2", "markers": "AUDIT, SECURITY"},
+		},
+	})
+	assert.NoError(t, err)
+
+	ctx.GetLogger("a/b").(api.MarkerWriter).WithMarker("LOGIN").Info("login")
+	ctx.GetLogger("c").(api.MarkerWriter).WithMarker("SECURITY").(api.MarkerWriter).WithMarker("X").Warn("denied")
+	ctx.GetLogger("c").(api.MarkerWriter).WithMarker("SECURITY").Debug("disabled")
+	ctx.GetLogger("c").(api.MarkerWriter).WithMarker("X").Info("other")
+
+	m := ctx.Manager().(*defManager)
+	assert.Equal(t, "c|SECURITY,X|denied\nc|X|other\n", m.outputs["m1"].(*memoryOutput).String())
+	assert.Equal(t, "a/b|LOGIN|login\n", m.outputs["m2"].(*memoryOutput).String())
+	assert.Equal(t, "a/b|LOGIN|login|TestMarkerRouting\nc|SECURITY,X|denied|TestMarkerRouting\n",
+		m.outputs["audit"].(*memoryOutput).String())
+}
+
+func TestMarkerRoutingConfigFailed(t *testing.T) {
+	cfg := &api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "info", OutputNames: []string{"m1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+		Outputs: []api.CfgOutput{
+			{"type": "memory", "name": "m1", "format": "f1"},
+			{"type": "memory", "name": "audit", "format": "f1", "markers": "AUDIT"},
+		},
+	}
+	ctx, err := NewLoggerContext(cfg)
+	assert.NoError(t, err)
+	m := ctx.Manager().(*defManager)
+	audit := m.outputs["audit"]
+
+	// the old config, routes and outputs are kept if the routes of the new one failed to create
+	err = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "warn", OutputNames: []string{"m1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+		Outputs: []api.CfgOutput{
+			{"type": "memory", "name": "m1", "format": "f1"},
+			{"type": "memory", "name": "audit", "format": "f1", "markers": "AUDIT"},
+			{"type": "console", "name": "bad", "format": "f1", "markers": "SECURITY", "dedup_window": "x"},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, cfg, m.config)
+	assert.NotContains(t, m.outputs, "bad")
+
+	log := ctx.GetLogger("a")
+	assert.Equal(t, api.Info, log.Level())
+	log.(api.MarkerWriter).WithMarker("AUDIT").Info("login")
+	assert.Equal(t, "login\n", audit.(*memoryOutput).String())
+}
@@ -20,38 +20,157 @@ const (
 	batchMinNum        = 20
 	batchMaxNum        = 500
 	flagClosed   int32 = 1
+
+	flushInterval      = 5 * time.Second
+	spoolRetryInterval = flushInterval
 )
 
 // ------------------------------------
 
+// errorReportOutput is the output which reports the errors of writing to the handler
+type errorReportOutput interface {
+	SetErrorHandler(h func(err error))
+}
+
+// eventWriter is the output which tells whether a event is written or dropped by its threshold, filters or sampler
+type eventWriter interface {
+	writeEvent(e *api.Event) (written bool, err error)
+}
+
+// asyncWriter is the output which tells whether it writes events asynchronously,
+// the errors of writing are not returned by Write of the asynchronous ones
+type asyncWriter interface {
+	async() bool
+}
+
+// writerOutput is the output that writes formatted events to a io.Writer,
+// the builtin outputs embed it to share the sync and async implementation.
+type writerOutput interface {
+	api.StatsOutput
+	Flush()
+	SetFilters(fs []api.Filter)
+	SetSampler(s *sampler)
+	SetErrorHandler(h func(err error))
+	Write(e *api.Event) error
+	eventWriter
+	asyncWriter
+	threshold() api.Level
+}
+
 type baseOutput struct {
-	w io.Writer
-	f api.Formatter
-	t api.Level //threshold
+	w  io.Writer
+	f  api.Formatter
+	t  api.Level    //threshold
+	fs []api.Filter // evaluated after the threshold
+	s  *sampler     // evaluated after the filters
+	h  func(err error)
+
+	send func(e *api.Event) // Send of the output embedding it, the summary of the sampler is sent by it
+
+	events  uint64 // atomic
+	bytes   uint64 // atomic
+	dropped uint64 // atomic
+	errors  uint64 // atomic
 }
 
 // NewBaseOutput ...
 func NewBaseOutput(w io.Writer, threshold api.Level) api.Output {
+	return newBaseOutput(w, threshold)
+}
+
+func newBaseOutput(w io.Writer, threshold api.Level) writerOutput {
 	b := &baseOutput{w: w, t: threshold}
+	b.send = b.Send
 	return b
 }
 
 // Send a event to output
 func (o *baseOutput) Send(e *api.Event) {
-	if e.Level < o.t {
-		return
+	_ = o.Write(e)
+}
+
+// Write a event to output and return the error of the writer, the error is also reported to the handler
+func (o *baseOutput) Write(e *api.Event) error {
+	_, err := o.writeEvent(e)
+	return err
+}
+
+// writeEvent write a event like Write and return whether it is written
+func (o *baseOutput) writeEvent(e *api.Event) (written bool, err error) {
+	if !o.accept(e) {
+		atomic.AddUint64(&o.dropped, 1)
+		return false, nil
 	}
 
+	var n int
 	if o.f != nil {
-		o.w.Write([]byte(o.f.Format(e)))
-		return
+		n, err = o.w.Write([]byte(o.f.Format(e)))
+	} else {
+		n, err = fmt.Fprintf(o.w, defaultLayout,
+			e.Level.String(),
+			e.Time.Format(defaultTimeLayout),
+			e.Name,
+			e.Message())
+	}
+	if err != nil {
+		o.failed(1, markStatusError(err, isStatusRecord(e)))
+		return false, err
 	}
+	o.written(1, n)
+	return true, nil
+}
+
+// accept return whether the event passes the threshold, the filters and the sampler
+func (o *baseOutput) accept(e *api.Event) bool {
+	return acceptEvent(e, o.t, o.fs, o.s)
+}
 
-	fmt.Fprintf(o.w, defaultLayout,
-		e.Level.String(),
-		e.Time.Format(defaultTimeLayout),
-		e.Name,
-		e.Message())
+// acceptEvent return whether the event passes the threshold t, the filters fs and the sampler s if not nil,
+// the summary event of the sampler is always accepted
+func acceptEvent(e *api.Event, t api.Level, fs []api.Filter, s *sampler) bool {
+	if s == nil {
+		return e.Level >= t && filterChain(fs, e) != api.Deny
+	}
+	if isSummary(e) {
+		return true
+	}
+	return e.Level >= t && filterChain(fs, e) != api.Deny && s.allow(e)
+}
+
+// written add the number of events and bytes written to the statistics
+func (o *baseOutput) written(events int, bytes int) {
+	atomic.AddUint64(&o.events, uint64(events))
+	atomic.AddUint64(&o.bytes, uint64(bytes))
+}
+
+// failed add the number of events failed to write to the statistics and report the error
+func (o *baseOutput) failed(events int, err error) {
+	atomic.AddUint64(&o.errors, uint64(events))
+	o.report(err)
+}
+
+// report the error to the handler, or print it to stderr if not set
+func (o *baseOutput) report(err error) {
+	if o.h != nil {
+		o.h(err)
+	} else {
+		reportInternalError(err)
+	}
+}
+
+// SetErrorHandler set the handler of the errors of writing, the errors are printed to stderr if not set
+func (o *baseOutput) SetErrorHandler(h func(err error)) {
+	o.h = h
+}
+
+// Stats return the runtime statistics
+func (o *baseOutput) Stats() api.OutputStats {
+	return api.OutputStats{
+		Events:  atomic.LoadUint64(&o.events),
+		Bytes:   atomic.LoadUint64(&o.bytes),
+		Dropped: atomic.LoadUint64(&o.dropped),
+		Errors:  atomic.LoadUint64(&o.errors),
+	}
 }
 
 // SetFormatter set a formatter for output
@@ -59,6 +178,20 @@ func (o *baseOutput) SetFormatter(f api.Formatter) {
 	o.f = f
 }
 
+// SetFilters set the filters evaluated after the threshold
+func (o *baseOutput) SetFilters(fs []api.Filter) {
+	o.fs = fs
+}
+
+// threshold return the level below which the events are dropped
+func (o *baseOutput) threshold() api.Level {
+	return o.t
+}
+
+func (o *baseOutput) async() bool {
+	return false
+}
+
 func (o *baseOutput) CallerInfoFlag() int {
 	if o.f != nil {
 		return o.f.CallerInfoFlag()
@@ -66,9 +199,24 @@ func (o *baseOutput) CallerInfoFlag() int {
 	return ciNoneFlog
 }
 
+// SetSampler set the sampler and start its summary, nil means no sampling
+func (o *baseOutput) SetSampler(s *sampler) {
+	if s != nil {
+		o.s = s
+		s.start(o.send)
+	}
+}
+
+// Flush do nothing, the event is written when sending
+func (o *baseOutput) Flush() {
+
+}
+
 // Close ...
 func (o *baseOutput) Close() {
-
+	if o.s != nil {
+		o.s.close()
+	}
 }
 
 // ------------------------------------
@@ -108,23 +256,44 @@ func GetThresholdLvl(str string) api.Level {
 
 // NewAsyncOutput ...
 func NewAsyncOutput(w io.Writer, threshold api.Level, queueSize int, batchNum int) api.Output {
+	return newAsyncOutput(w, threshold, queueSize, batchNum, nil)
+}
+
+// newAsyncOutput return a async output which appends the batches of events to the spool before writing them
+// if sp is not nil
+func newAsyncOutput(w io.Writer, threshold api.Level, queueSize int, batchNum int, sp *spool) writerOutput {
 	o := &asyncOutput{
-		evtChan:  make(chan *api.Event, queueSize),
-		batchNum: batchNum,
+		evtChan:   make(chan *api.Event, queueSize),
+		flushChan: make(chan chan struct{}),
+		done:      make(chan struct{}),
+		batchNum:  batchNum,
+		sp:        sp,
 	}
 	o.baseOutput = &baseOutput{w: w, t: threshold}
+	o.baseOutput.send = o.Send
+	if sp != nil {
+		// the errors of the spool are reported by the error handler of the output
+		sp.report = o.report
+	}
+	// add before the loop started, so Close waits the loop even if called immediately
+	o.wait.Add(1)
 	go o.loop()
 	return o
 }
 
 type asyncOutput struct {
 	*baseOutput
-	evtChan  chan *api.Event
-	batchNum int
-	currNum  int
-	buf      bytes.Buffer
-	wait     sync.WaitGroup
-	closed   int32
+	evtChan   chan *api.Event
+	flushChan chan chan struct{} // flush requests, closed by the loop when flushed
+	done      chan struct{}      // closed when the loop quit
+	batchNum  int
+	currNum   int
+	records   int // the status records buffered, the errors of writing them only are not recorded again
+	buf       bytes.Buffer
+	sp        *spool    // the write-ahead queue of the batches, nil if not enabled
+	retryAt   time.Time // the spool is replayed by the full batches after it, set when failed to replay
+	wait      sync.WaitGroup
+	closed    int32
 }
 
 func (o *asyncOutput) Send(e *api.Event) {
@@ -134,48 +303,166 @@ func (o *asyncOutput) Send(e *api.Event) {
 	o.evtChan <- e
 }
 
+func (o *asyncOutput) async() bool {
+	return true
+}
+
+// Write queue the event as Send, the error of writing can not be reported asynchronously
+func (o *asyncOutput) Write(e *api.Event) error {
+	o.Send(e)
+	return nil
+}
+
+// writeEvent queue the event as Send, it is regarded as written if not below the threshold,
+// the filters and sampler are evaluated when it is written asynchronously
+func (o *asyncOutput) writeEvent(e *api.Event) (bool, error) {
+	o.Send(e)
+	return e.Level >= o.t, nil
+}
+
 func (o *asyncOutput) Close() {
 	// support duplicate call Close method
 	if atomic.LoadInt32(&o.closed) == flagClosed {
 		return
 	}
+	if o.s != nil {
+		o.s.close()
+	}
 	o.evtChan <- nil
 	o.wait.Wait()
 	atomic.StoreInt32(&o.closed, flagClosed)
 	close(o.evtChan)
 }
 
+// Flush write all queued events to the writer and wait until done
+func (o *asyncOutput) Flush() {
+	req := make(chan struct{})
+	select {
+	case o.flushChan <- req:
+		select {
+		case <-req:
+		case <-o.done:
+		}
+	case <-o.done:
+	}
+}
+
 func (o *asyncOutput) flush() {
+	if o.sp != nil {
+		o.spool()
+		o.replay()
+		return
+	}
+	if o.currNum == 0 {
+		return
+	}
 	bs := o.buf.Bytes()
-	o.w.Write(bs)
+	if n, err := o.w.Write(bs); err != nil {
+		o.failed(o.currNum, o.markStatusError(err))
+	} else {
+		o.written(o.currNum, n)
+	}
 	o.buf.Truncate(0)
-	o.currNum = 0
+	o.currNum, o.records = 0, 0
+}
+
+// markStatusError return the error marked if the buffered events are all status records
+func (o *asyncOutput) markStatusError(err error) error {
+	return markStatusError(err, o.records == o.currNum)
+}
+
+// flushBatch write the full batch, or append it to the spool and replay the spool unless failed to replay
+// in the retry interval
+func (o *asyncOutput) flushBatch() {
+	if o.sp == nil {
+		o.flush()
+		return
+	}
+	o.spool()
+	if time.Now().After(o.retryAt) {
+		o.replay()
+	}
+}
+
+// spool append the buffered events to the spool as a record ahead of writing them
+func (o *asyncOutput) spool() {
+	if o.currNum == 0 {
+		return
+	}
+	dropped, err := o.sp.append(o.buf.Bytes(), o.currNum)
+	atomic.AddUint64(&o.dropped, uint64(dropped))
+	if err != nil && err != errSpoolClosed {
+		o.failed(o.currNum, o.markStatusError(err))
+	}
+	o.buf.Truncate(0)
+	o.currNum, o.records = 0, 0
+}
+
+// replay write the spooled events to the writer, the events failed to write are kept in the spool
+// and replayed by the next flush
+func (o *asyncOutput) replay() {
+	events, n, err := o.sp.replay(o.w, o.batchNum)
+	o.written(events, n)
+	if err != nil {
+		o.retryAt = time.Now().Add(spoolRetryInterval)
+		o.report(err)
+	}
 }
 
 func (o *asyncOutput) loop() {
-	o.wait.Add(1)
 	defer o.wait.Done()
+	defer close(o.done)
 
-	tick := time.NewTicker(5 * time.Second)
+	// the events spooled by the last run are replayed first
+	if o.sp != nil {
+		defer o.sp.close()
+		o.replay()
+	}
+
+	tick := time.NewTicker(flushInterval)
 	defer tick.Stop()
 
 	for {
 		select {
 		case <-tick.C:
 			o.flush()
+		case req := <-o.flushChan:
+			// consume the events queued before the flush request
+			quit := false
+			for n := len(o.evtChan); n > 0 && !quit; n-- {
+				quit = !o.handle(<-o.evtChan)
+			}
+			o.flush()
+			close(req)
+			if quit {
+				return
+			}
 		case evt := <-o.evtChan:
-			if evt == nil {
+			if !o.handle(evt) {
 				o.flush()
 				return
 			}
+		}
+	}
+}
 
-			if evt.Level >= o.t {
-				o.buf.Write(o.f.Format(evt))
-				o.currNum++
-				if o.currNum >= o.batchNum {
-					o.flush()
-				}
-			}
+// handle buffer a event, return false if it is the quit event
+func (o *asyncOutput) handle(evt *api.Event) bool {
+	if evt == nil {
+		return false
+	}
+
+	if o.accept(evt) {
+		o.buf.Write(o.f.Format(evt))
+		o.currNum++
+		if isStatusRecord(evt) {
+			o.records++
+		}
+		if o.currNum >= o.batchNum {
+			o.flushBatch()
 		}
+	} else {
+		atomic.AddUint64(&o.dropped, 1)
 	}
+	return true
 }
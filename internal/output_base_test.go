@@ -31,3 +31,19 @@ func TestAsyncOutput(t *testing.T) {
 	assert.Equal(t, buf.String(), "test|INF >> abcdef")
 	aop.Close()
 }
+
+func TestAsyncOutputFlush(t *testing.T) {
+	var buf bytes.Buffer
+	aop := newAsyncOutput(&buf, api.Info, 10, 10, nil)
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{msg}|"})
+	aop.SetFormatter(f)
+	for i := 0; i < 3; i++ {
+		aop.Send(&api.Event{Format: "abc", Level: api.Info, Ctx: context.Background()})
+	}
+	aop.Flush()
+	assert.Equal(t, "abc|abc|abc|", buf.String())
+	assert.Equal(t, api.OutputStats{Events: 3, Bytes: 12}, aop.Stats())
+
+	aop.Close()
+	aop.Flush()
+}
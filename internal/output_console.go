@@ -11,18 +11,31 @@ const (
 )
 
 type consoleOutput struct {
-	api.Output
+	writerOutput
 }
 
 // NewConsoleOutput return a output instance that it print message to stdio
 func NewConsoleOutput(cfg api.CfgOutput) (api.Output, error) {
 	r := &consoleOutput{}
+	s, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	window, err := getDedupWindow(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := newSpool(cfg)
+	if err != nil {
+		return nil, err
+	}
 	if cfg != nil && cfg["async"] == "true" {
-		r.Output = NewAsyncOutput(os.Stdout, GetThresholdLvl(cfg["threshold"]),
-			GetQueueSize(cfg["queue_size"]), GetBatchNum(cfg["batch_num"]))
+		r.writerOutput = newAsyncOutput(os.Stdout, GetThresholdLvl(cfg["threshold"]),
+			GetQueueSize(cfg["queue_size"]), GetBatchNum(cfg["batch_num"]), sp)
 	} else {
-		r.Output = NewBaseOutput(os.Stdout, GetThresholdLvl(cfg["threshold"]))
+		r.writerOutput = newBaseOutput(os.Stdout, GetThresholdLvl(cfg["threshold"]))
 	}
-
+	r.writerOutput.SetSampler(s)
+	r.writerOutput = newDedupOutput(r.writerOutput, window)
 	return r, nil
 }
@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+// dedupOutput wraps a output and collapses the consecutive identical events, which have the
+// same logger, level and formatted message within the window after the first one, into the
+// first event followed by a summary event like 'last message repeated N times'.
+// The summary is sent by the next different event, or when the window after the first
+// collapsed event elapses.
+type dedupOutput struct {
+	writerOutput
+	window time.Duration
+
+	sync.Mutex
+	last       *api.Event  // the last event sent to the wrapped output
+	msg        string      // the formatted message of last
+	repeated   int         // the events collapsed into last and not reported
+	lastRepeat *api.Event  // the last collapsed event
+	timer      *time.Timer // send the summary when the window elapses, nil if nothing collapsed
+	timerSeq   uint64      // increased by each timer, a fired timer is ignored if it is not the current one
+	suppressed uint64      // atomic
+}
+
+// getDedupWindow return the window configured by 'dedup_window', 0 if not configured
+func getDedupWindow(cfg api.CfgOutput) (time.Duration, error) {
+	str, ok := cfg["dedup_window"]
+	if !ok {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(str)
+	if err != nil || window <= 0 {
+		return 0, fmt.Errorf("invalid dedup_window %q of output[%s]", str, cfg.Name())
+	}
+	return window, nil
+}
+
+// newDedupOutput return the output wraps o if window is not 0, otherwise o
+func newDedupOutput(o writerOutput, window time.Duration) writerOutput {
+	if window == 0 {
+		return o
+	}
+	return &dedupOutput{writerOutput: o, window: window}
+}
+
+// Send a event to the wrapped output unless it repeats the last one
+func (o *dedupOutput) Send(e *api.Event) {
+	_ = o.Write(e)
+}
+
+// Write a event to the wrapped output unless it repeats the last one, return the error of the wrapped output
+func (o *dedupOutput) Write(e *api.Event) error {
+	_, err := o.writeEvent(e)
+	return err
+}
+
+// writeEvent write a event like Write and return whether it is written, the collapsed one is not written
+func (o *dedupOutput) writeEvent(e *api.Event) (bool, error) {
+	// the events dropped by the threshold are not formatted nor compared
+	if e.Level < o.writerOutput.threshold() {
+		return o.writerOutput.writeEvent(e)
+	}
+
+	msg := e.Message()
+	o.Lock()
+	defer o.Unlock()
+	if o.last != nil && e.Name == o.last.Name && e.Level == o.last.Level && msg == o.msg &&
+		e.Time.Sub(o.last.Time) <= o.window {
+		o.repeated++
+		o.lastRepeat = e
+		atomic.AddUint64(&o.suppressed, 1)
+		if o.timer == nil {
+			o.timerSeq++
+			seq := o.timerSeq
+			o.timer = time.AfterFunc(o.window, func() { o.expire(seq) })
+		}
+		return false, nil
+	}
+
+	o.sendRepeated()
+	written, err := o.writerOutput.writeEvent(e)
+	// the events dropped by the filters or the sampler do not collapse the next ones
+	if written {
+		o.last, o.msg = e, msg
+	}
+	return written, err
+}
+
+// expire send the summary when the window of the timer seq elapses, the next event is not collapsed then
+func (o *dedupOutput) expire(seq uint64) {
+	o.Lock()
+	defer o.Unlock()
+	// the timer is stopped or replaced after it fired
+	if o.timer == nil || o.timerSeq != seq {
+		return
+	}
+	o.sendRepeated()
+	o.last, o.msg = nil, ""
+}
+
+// sendRepeated send the summary event of the collapsed events if any, the caller must hold the lock
+func (o *dedupOutput) sendRepeated() {
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	if o.repeated == 0 {
+		return
+	}
+	e := o.lastRepeat
+	o.writerOutput.Send(&api.Event{
+		Time:      e.Time,
+		Name:      e.Name,
+		Level:     e.Level,
+		Format:    "last message repeated %d times",
+		Arguments: []interface{}{o.repeated},
+		CallDepth: e.CallDepth,
+		Ctx:       e.Ctx,
+	})
+	o.repeated, o.lastRepeat = 0, nil
+}
+
+// Stats return the statistics of the wrapped output, the collapsed events are counted as dropped
+func (o *dedupOutput) Stats() api.OutputStats {
+	stats := o.writerOutput.Stats()
+	stats.Dropped += atomic.LoadUint64(&o.suppressed)
+	return stats
+}
+
+// Flush send the pending summary event and flush the wrapped output
+func (o *dedupOutput) Flush() {
+	o.Lock()
+	o.sendRepeated()
+	o.Unlock()
+	o.writerOutput.Flush()
+}
+
+// Close send the pending summary event and close the wrapped output
+func (o *dedupOutput) Close() {
+	o.Lock()
+	o.sendRepeated()
+	o.Unlock()
+	o.writerOutput.Close()
+}
@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestDedupOutput(t *testing.T) {
+	_, err := getDedupWindow(api.CfgOutput{"dedup_window": "x"})
+	assert.Error(t, err)
+	window, err := getDedupWindow(api.CfgOutput{"dedup_window": "1s"})
+	assert.NoError(t, err)
+
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{module}|%{lvl}|%{msg}\n"})
+	now := time.Unix(100, 0)
+	send := func(o api.Output, d time.Duration, name string, lvl api.Level, msg string) {
+		o.Send(&api.Event{Time: now.Add(d), Name: name, Level: lvl, Format: "%s", Arguments: []interface{}{msg}, Ctx: context.Background()})
+	}
+
+	for _, async := range []bool{false, true} {
+		var buf bytes.Buffer
+		var wo writerOutput
+		if async {
+			wo = newAsyncOutput(&buf, api.All, 100, 100, nil)
+		} else {
+			wo = newBaseOutput(&buf, api.All)
+		}
+		wo.SetFormatter(f)
+		o := newDedupOutput(wo, window)
+
+		send(o, 0, "m", api.Info, "a")
+		send(o, 100*time.Millisecond, "m", api.Info, "a")
+		send(o, 200*time.Millisecond, "m", api.Info, "a")
+		send(o, 300*time.Millisecond, "m", api.Warn, "a")
+		send(o, 400*time.Millisecond, "n", api.Warn, "a")
+		send(o, 500*time.Millisecond, "n", api.Warn, "b")
+		send(o, 600*time.Millisecond, "n", api.Warn, "b")
+		// out of the window after the first one
+		send(o, 1600*time.Millisecond, "n", api.Warn, "b")
+		send(o, 1700*time.Millisecond, "n", api.Warn, "b")
+		o.Close()
+
+		assert.Equal(t, "m|INF|a\nm|INF|last message repeated 2 times\nm|WRN|a\nn|WRN|a\nn|WRN|b\n"+
+			"n|WRN|last message repeated 1 times\nn|WRN|b\nn|WRN|last message repeated 1 times\n", buf.String(), async)
+		assert.Equal(t, uint64(4), o.Stats().Dropped, async)
+	}
+}
+
+func TestDedupOutputFiltered(t *testing.T) {
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{lvl}|%{msg}\n"})
+	ft, _ := NewFieldFilter(api.CfgFilter{"type": "field", "name": "x", "key": "drop", "value": "1",
+		"on_match": "deny", "on_mismatch": "neutral"})
+	var buf bytes.Buffer
+	wo := newBaseOutput(&buf, api.All)
+	wo.SetFormatter(f)
+	wo.SetFilters([]api.Filter{ft})
+	o := newDedupOutput(wo, time.Second)
+
+	// the event dropped by the filter does not collapse the next identical one
+	now := time.Now()
+	o.Send(&api.Event{Time: now, Level: api.Info, Format: "a", Ctx: api.WithFields(context.Background(), api.Field{Key: "drop", Value: 1})})
+	o.Send(&api.Event{Time: now, Level: api.Info, Format: "a", Ctx: context.Background()})
+	o.Send(&api.Event{Time: now, Level: api.Info, Format: "a", Ctx: context.Background()})
+	o.Close()
+
+	assert.Equal(t, "INF|a\nINF|last message repeated 1 times\n", buf.String())
+}
+
+// syncBuffer is a bytes.Buffer safe for the writes of the timer
+type syncBuffer struct {
+	sync.Mutex
+	b bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.Lock()
+	defer s.Unlock()
+	return s.b.String()
+}
+
+// countStringer counts the calls of String
+type countStringer struct{ n int32 }
+
+func (c *countStringer) String() string {
+	atomic.AddInt32(&c.n, 1)
+	return "s"
+}
+
+func TestDedupOutputWindow(t *testing.T) {
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{lvl}|%{msg}\n"})
+	var buf syncBuffer
+	wo := newBaseOutput(&buf, api.Info)
+	wo.SetFormatter(f)
+	o := newDedupOutput(wo, 50*time.Millisecond)
+	defer o.Close()
+
+	// the events below the threshold are not formatted
+	c := &countStringer{}
+	o.Send(&api.Event{Time: time.Now(), Level: api.Debug, Format: "%s", Arguments: []interface{}{c}, Ctx: context.Background()})
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.n))
+	assert.Equal(t, uint64(1), o.Stats().Dropped)
+
+	// the summary is sent when the window elapses without a different event
+	for i := 0; i < 3; i++ {
+		o.Send(&api.Event{Time: time.Now(), Level: api.Info, Format: "a", Ctx: context.Background()})
+	}
+	assert.Equal(t, "INF|a\n", buf.String())
+	for i := 0; i < 100 && buf.String() == "INF|a\n"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, "INF|a\nINF|last message repeated 2 times\n", buf.String())
+
+	// a new window is started by the next event
+	o.Send(&api.Event{Time: time.Now(), Level: api.Info, Format: "a", Ctx: context.Background()})
+	assert.Equal(t, "INF|a\nINF|last message repeated 2 times\nINF|a\n", buf.String())
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	typeFailover = "failover"
+
+	defaultFailoverRetryInterval = 30 * time.Second
+)
+
+type failoverTarget struct {
+	name    string
+	output  api.ErrorOutput
+	reached uint64 // the events reached the target
+}
+
+// failoverOutput writes events to the primary target, switches to the next secondary target
+// on write errors, and retries the targets before the active one periodically.
+type failoverOutput struct {
+	targets       []*failoverTarget // the primary and the ordered secondaries
+	retryInterval time.Duration
+
+	sync.Mutex
+	active    int       // index of the target written currently
+	lastRetry time.Time // the time of the last switching or retrying the targets before the active one
+
+	events  uint64 // atomic
+	dropped uint64 // atomic
+}
+
+// newFailoverCreator return the creator of failover outputs, lookup return the target output by name
+// and is called when the failover output created:
+//
+//	primary         the name of the primary output
+//	secondaries     the names of the secondary outputs separated by comma
+//	retry_interval  the interval of retrying the primary and the secondaries before the active one
+//	                after switched, default is 30s
+//
+// The targets must report write errors, so the asynchronous outputs can not be a target.
+func newFailoverCreator(lookup func(name string) (api.Output, error)) api.OutputFuncCreator {
+	return func(cfg api.CfgOutput) (api.Output, error) {
+		o := &failoverOutput{retryInterval: defaultFailoverRetryInterval}
+		names := append([]string{cfg["primary"]}, cfg.Names("secondaries")...)
+		if names[0] == "" || len(names) < 2 {
+			return nil, fmt.Errorf("not set primary and secondaries of output[%s]", cfg.Name())
+		}
+		for _, name := range names {
+			if name == cfg.Name() {
+				return nil, fmt.Errorf("output[%s] can not be the target of itself", name)
+			}
+			op, err := lookup(name)
+			if err != nil {
+				return nil, err
+			}
+			eo, ok := op.(api.ErrorOutput)
+			if !ok {
+				return nil, fmt.Errorf("output[%s] not support reporting errors for failover", name)
+			}
+			if ao, ok := op.(asyncWriter); ok && ao.async() {
+				return nil, fmt.Errorf("async output[%s] can not be the target of failover", name)
+			}
+			o.targets = append(o.targets, &failoverTarget{name: name, output: eo})
+		}
+		if s, ok := cfg["retry_interval"]; ok {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid retry_interval %q of output[%s]", s, cfg.Name())
+			}
+			o.retryInterval = d
+		}
+		return o, nil
+	}
+}
+
+// Send the event to the active target, the following targets are tried if failed
+func (o *failoverOutput) Send(e *api.Event) {
+	_ = o.Write(e)
+}
+
+// Write the event to the active target, return the error of the last target if all failed.
+// The targets before the active one are tried in order first when the retry interval elapses.
+func (o *failoverOutput) Write(e *api.Event) (err error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.active != 0 && time.Since(o.lastRetry) >= o.retryInterval {
+		o.lastRetry = time.Now()
+		for i := 0; i < o.active; i++ {
+			if err = o.writeTo(i, e); err == nil {
+				return nil
+			}
+		}
+	}
+	for i := o.active; i < len(o.targets); i++ {
+		if err = o.writeTo(i, e); err == nil {
+			return nil
+		}
+	}
+	atomic.AddUint64(&o.dropped, 1)
+	return err
+}
+
+// writeTo write the event to the i-th target and switch to it if succeeded, the caller must hold the lock.
+// The event dropped by the threshold, filters or sampler of the target is not counted as reached.
+func (o *failoverOutput) writeTo(i int, e *api.Event) (err error) {
+	t := o.targets[i]
+	written := false
+	if w, ok := t.output.(eventWriter); ok {
+		written, err = w.writeEvent(e)
+	} else if err = t.output.Write(e); err == nil {
+		written = true
+	}
+	if err != nil {
+		return err
+	}
+	if i != o.active {
+		o.active = i
+		o.lastRetry = time.Now()
+	}
+	if written {
+		atomic.AddUint64(&t.reached, 1)
+		atomic.AddUint64(&o.events, 1)
+	} else {
+		atomic.AddUint64(&o.dropped, 1)
+	}
+	return nil
+}
+
+// SetFormatter do nothing, the targets use their own formatters
+func (o *failoverOutput) SetFormatter(_ api.Formatter) {
+
+}
+
+// CallerInfoFlag return the max caller info flag of the targets
+func (o *failoverOutput) CallerInfoFlag() int {
+	flag := ciNoneFlog
+	for _, t := range o.targets {
+		if f := t.output.CallerInfoFlag(); f > flag {
+			flag = f
+		}
+	}
+	return flag
+}
+
+// Stats return the events written to any target, the events failed on all targets or dropped by
+// the target written as dropped, and the events reached each target
+func (o *failoverOutput) Stats() api.OutputStats {
+	stats := api.OutputStats{
+		Events:  atomic.LoadUint64(&o.events),
+		Dropped: atomic.LoadUint64(&o.dropped),
+		Targets: make(map[string]uint64, len(o.targets)),
+	}
+	for _, t := range o.targets {
+		stats.Targets[t.name] = atomic.LoadUint64(&t.reached)
+	}
+	return stats
+}
+
+// Close do nothing, the targets are closed by the manager
+func (o *failoverOutput) Close() {
+
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+// switchWriter fails to write when broken
+type switchWriter struct {
+	bytes.Buffer
+	broken int32
+}
+
+func (w *switchWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(&w.broken) != 0 {
+		return 0, errors.New("broken")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFailoverOutput(t *testing.T) {
+	writers := map[string]*switchWriter{"p": {}, "s1": {}, "s2": {}}
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	m := ctx.Manager()
+	m.RegisterOutputCreator("switch", func(cfg api.CfgOutput) (api.Output, error) {
+		return newBaseOutput(writers[cfg.Name()], api.All), nil
+	})
+	err = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"fo"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}|"}},
+		Outputs: []api.CfgOutput{
+			{"type": "switch", "name": "p", "format": "f1"},
+			{"type": "switch", "name": "s1", "format": "f1"},
+			{"type": "switch", "name": "s2", "format": "f1"},
+			{"type": "failover", "name": "fo", "format": "f1", "primary": "p", "secondaries": "s1, s2", "retry_interval": "50ms"},
+		},
+	})
+	assert.NoError(t, err)
+
+	log := ctx.GetLogger("failover")
+	log.Info("1")
+	atomic.StoreInt32(&writers["p"].broken, 1)
+	log.Info("2")
+	atomic.StoreInt32(&writers["s1"].broken, 1)
+	log.Info("3")
+	atomic.StoreInt32(&writers["s2"].broken, 1)
+	log.Info("4")
+	atomic.StoreInt32(&writers["s2"].broken, 0)
+	log.Info("5")
+	// switch back to the primary after the retry interval
+	atomic.StoreInt32(&writers["p"].broken, 0)
+	log.Info("6")
+	time.Sleep(60 * time.Millisecond)
+	log.Info("7")
+	log.Info("8")
+
+	assert.Equal(t, "1|7|8|", writers["p"].String())
+	assert.Equal(t, "2|", writers["s1"].String())
+	assert.Equal(t, "3|5|6|", writers["s2"].String())
+	fo := m.(*defManager).outputs["fo"].(*failoverOutput)
+	assert.Equal(t, api.OutputStats{Events: 7, Dropped: 1, Targets: map[string]uint64{"p": 3, "s1": 1, "s2": 3}}, fo.Stats())
+}
+
+func TestFailoverOutputConfig(t *testing.T) {
+	lookup := func(name string) (api.Output, error) {
+		switch name {
+		case "m1":
+			return NewMemoryOutput(nil)
+		case "c1":
+			return NewConsoleOutput(api.CfgOutput{"async": "true"})
+		}
+		return nil, errors.New("not found")
+	}
+	create := newFailoverCreator(lookup)
+	for _, cfg := range []api.CfgOutput{
+		{"name": "fo", "primary": "m1"},
+		{"name": "fo", "primary": "fo", "secondaries": "m1"},
+		{"name": "fo", "primary": "m1", "secondaries": "x"},
+		{"name": "fo", "primary": "m1", "secondaries": "m1", "retry_interval": "x"},
+		{"name": "fo", "primary": "m1", "secondaries": "c1"},
+	} {
+		_, err := create(cfg)
+		assert.Error(t, err, cfg)
+	}
+
+	op, err := create(api.CfgOutput{"name": "fo", "primary": "m1", "secondaries": "m1"})
+	assert.NoError(t, err)
+	assert.Len(t, op.(*failoverOutput).targets, 2)
+}
+
+func TestFailoverOutputRecover(t *testing.T) {
+	writers := map[string]*switchWriter{"p": {}, "s1": {}, "s2": {}}
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{msg}|"})
+	lookup := func(name string) (api.Output, error) {
+		threshold := api.All
+		if name == "p" {
+			threshold = api.Warn
+		}
+		o := newBaseOutput(writers[name], threshold)
+		o.SetFormatter(f)
+		return o, nil
+	}
+	op, err := newFailoverCreator(lookup)(api.CfgOutput{"name": "fo", "primary": "p", "secondaries": "s1,s2",
+		"retry_interval": "50ms"})
+	assert.NoError(t, err)
+	fo := op.(*failoverOutput)
+	send := func(lvl api.Level, msg string) {
+		fo.Send(&api.Event{Level: lvl, Format: msg})
+	}
+
+	// the event dropped by the threshold of the target does not reach it
+	send(api.Info, "1")
+	send(api.Warn, "2")
+	atomic.StoreInt32(&writers["p"].broken, 1)
+	atomic.StoreInt32(&writers["s1"].broken, 1)
+	send(api.Warn, "3")
+	// the secondary before the active one is retried after the retry interval
+	atomic.StoreInt32(&writers["s1"].broken, 0)
+	send(api.Warn, "4")
+	time.Sleep(60 * time.Millisecond)
+	send(api.Warn, "5")
+	send(api.Warn, "6")
+
+	assert.Equal(t, "2|", writers["p"].String())
+	assert.Equal(t, "3|4|", writers["s2"].String())
+	assert.Equal(t, "5|6|", writers["s1"].String())
+	assert.Equal(t, api.OutputStats{Events: 5, Dropped: 1, Targets: map[string]uint64{"p": 1, "s1": 2, "s2": 2}}, fo.Stats())
+}
@@ -11,7 +11,7 @@ const (
 )
 
 type memoryOutput struct {
-	api.Output
+	writerOutput
 	buf bytes.Buffer
 }
 
@@ -20,8 +20,13 @@ func (o *memoryOutput) String() string {
 }
 
 // NewMemoryOutput return a output instance that it print message to buffer
-func NewMemoryOutput(_ api.CfgOutput) (api.Output, error) {
+func NewMemoryOutput(cfg api.CfgOutput) (api.Output, error) {
+	s, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
 	r := &memoryOutput{}
-	r.Output = NewBaseOutput(&r.buf, api.All)
+	r.writerOutput = newBaseOutput(&r.buf, api.All)
+	r.writerOutput.SetSampler(s)
 	return r, nil
 }
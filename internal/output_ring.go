@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	typeRing = "ring"
+
+	defaultRingSize = 100
+)
+
+// ringOutput keeps the last events in memory, and sends them followed by the trigger event
+// to the target when a event at or above the trigger level arrives.
+type ringOutput struct {
+	target  api.Output
+	t       api.Level // threshold
+	trigger api.Level
+
+	sync.Mutex
+	ring []*api.Event // circular buffer of the last events
+	next int          // index of the next event buffered
+	full bool         // whether the buffer wraps around
+
+	events  uint64 // atomic
+	dropped uint64 // atomic, the events evicted from the buffer
+}
+
+// newRingCreator return the creator of ring outputs, lookup return the target output by name
+// and is called when the ring output created:
+//
+//	target         the name of the output which the buffered events are sent to
+//	size           the number of the last events buffered, default is 100
+//	trigger_level  the level which triggers sending, default is error
+//	threshold      the events below it are neither buffered nor trigger sending
+func newRingCreator(lookup func(name string) (api.Output, error)) api.OutputFuncCreator {
+	return func(cfg api.CfgOutput) (api.Output, error) {
+		name := cfg["target"]
+		if name == "" {
+			return nil, fmt.Errorf("not set target of output[%s]", cfg.Name())
+		}
+		if name == cfg.Name() {
+			return nil, fmt.Errorf("output[%s] can not be the target of itself", name)
+		}
+		target, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		size := defaultRingSize
+		if s, ok := cfg["size"]; ok {
+			if size, err = strconv.Atoi(s); err != nil || size <= 0 {
+				return nil, fmt.Errorf("invalid size %q of output[%s]", s, cfg.Name())
+			}
+		}
+		trigger := api.Error
+		if s, ok := cfg["trigger_level"]; ok {
+			if trigger = api.LevelFrom(s); trigger == api.Uninitialized {
+				return nil, fmt.Errorf("invalid trigger_level %q of output[%s]", s, cfg.Name())
+			}
+		}
+		return &ringOutput{
+			target:  target,
+			t:       GetThresholdLvl(cfg["threshold"]),
+			trigger: trigger,
+			ring:    make([]*api.Event, size),
+		}, nil
+	}
+}
+
+// Send buffer the event, or send the buffered events and it to the target if at or above the trigger level
+func (o *ringOutput) Send(e *api.Event) {
+	if e.Level < o.t {
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	if e.Level < o.trigger {
+		o.buffer(e)
+		return
+	}
+
+	history := o.history()
+	for _, h := range history {
+		o.target.Send(h)
+	}
+	o.target.Send(e)
+	atomic.AddUint64(&o.events, uint64(len(history)+1))
+}
+
+// buffer a snapshot of the event, the oldest one is dropped if full, the caller must hold the lock
+func (o *ringOutput) buffer(e *api.Event) {
+	if o.full {
+		atomic.AddUint64(&o.dropped, 1)
+	}
+	// the message is rendered now, the arguments may be changed before sent
+	snap := *e
+	snap.Format, snap.Arguments = e.Message(), nil
+	o.ring[o.next] = &snap
+	o.next++
+	if o.next == len(o.ring) {
+		o.next, o.full = 0, true
+	}
+}
+
+// history return the buffered events from the oldest and clear the buffer, the caller must hold the lock
+func (o *ringOutput) history() []*api.Event {
+	var es []*api.Event
+	if o.full {
+		es = append(es, o.ring[o.next:]...)
+	}
+	es = append(es, o.ring[:o.next]...)
+	for i := range o.ring {
+		o.ring[i] = nil
+	}
+	o.next, o.full = 0, false
+	return es
+}
+
+// SetFormatter do nothing, the target uses its own formatter
+func (o *ringOutput) SetFormatter(_ api.Formatter) {
+
+}
+
+// CallerInfoFlag return the caller info flag of the target, the caller info is got before buffered
+func (o *ringOutput) CallerInfoFlag() int {
+	return o.target.CallerInfoFlag()
+}
+
+// Stats return the events sent to the target, and the events evicted from the buffer as dropped,
+// the events below the threshold are not counted
+func (o *ringOutput) Stats() api.OutputStats {
+	return api.OutputStats{
+		Events:  atomic.LoadUint64(&o.events),
+		Dropped: atomic.LoadUint64(&o.dropped),
+	}
+}
+
+// Close drop the buffered events, the target is closed by the manager
+func (o *ringOutput) Close() {
+	o.Lock()
+	o.history()
+	o.Unlock()
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestRingOutput(t *testing.T) {
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	m := ctx.Manager()
+	err = m.SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"r1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{level}:%{msg}:%{shortfile}|"}},
+		Outputs: []api.CfgOutput{
+			{"type": "memory", "name": "m1", "format": "f1"},
+			{"type": "ring", "name": "r1", "format": "f1", "target": "m1", "size": "2", "threshold": "debug"},
+		},
+	})
+	assert.NoError(t, err)
+
+	log := ctx.GetLogger("ring")
+	args := []interface{}{"a"}
+	log.Tracef("%s", args...)
+	log.Debugf("%s", args...)
+	args[0] = "b"
+	log.Debugf("%s", args...)
+	log.Info("c")
+	mo := m.(*defManager).outputs["m1"].(*memoryOutput)
+	assert.Equal(t, "", mo.String())
+
+	// the last 2 events are sent before the trigger event, then the buffer is cleared
+	log.Error("d")
+	log.Critical("e")
+	assert.Equal(t, "DEBUG:b:output_ring_test.go|INFO:c:output_ring_test.go|ERROR:d:output_ring_test.go|"+
+		"CRITICAL:e:output_ring_test.go|", mo.String())
+	// the trace event below the threshold is not counted, the one evicted from the buffer is dropped
+	assert.Equal(t, api.OutputStats{Events: 4, Dropped: 1}, m.(*defManager).outputs["r1"].(api.StatsOutput).Stats())
+	ctx.Close()
+}
+
+func TestRingOutputConfig(t *testing.T) {
+	lookup := func(name string) (api.Output, error) {
+		if name == "m1" {
+			return NewMemoryOutput(nil)
+		}
+		return nil, errors.New("not found")
+	}
+	create := newRingCreator(lookup)
+	for _, cfg := range []api.CfgOutput{
+		{"name": "r1"},
+		{"name": "r1", "target": "r1"},
+		{"name": "r1", "target": "x"},
+		{"name": "r1", "target": "m1", "size": "0"},
+		{"name": "r1", "target": "m1", "trigger_level": "x"},
+	} {
+		_, err := create(cfg)
+		assert.Error(t, err, cfg)
+	}
+
+	op, err := create(api.CfgOutput{"name": "r1", "target": "m1", "trigger_level": "warn"})
+	assert.NoError(t, err)
+	assert.Len(t, op.(*ringOutput).ring, defaultRingSize)
+	assert.Equal(t, api.Warn, op.(*ringOutput).trigger)
+
+	// the ring output can not be a target
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	err = ctx.Manager().SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"r1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}"}},
+		Outputs: []api.CfgOutput{
+			{"type": "ring", "name": "r1", "format": "f1", "target": "r2"},
+			{"type": "ring", "name": "r2", "format": "f1", "target": "m1"},
+			{"type": "memory", "name": "m1", "format": "f1"},
+		},
+	})
+	assert.NoError(t, err)
+	dm := ctx.Manager().(*defManager)
+	dm.Lock()
+	_, err = dm.targetOutput("r2")
+	dm.Unlock()
+	assert.EqualError(t, err, "ring output[r2] can not be a target")
+	ctx.Close()
+}
@@ -15,7 +15,8 @@ const (
 )
 
 type rollingOutput struct {
-	api.Output
+	writerOutput
+	rw *rollingFileWriter
 }
 
 // NewRollingOutput return a output instance that it print message to stdio
@@ -25,6 +26,7 @@ func NewRollingOutput(cfg api.CfgOutput) (o api.Output, err error) {
 
 	fpath := cfg["file"]
 	rw := newRollingFileWriter(fpath, "")
+	r.rw = rw
 
 	rw.archiveType = rollingArchiveNone
 	switch cfg["archive"] {
@@ -63,15 +65,37 @@ func NewRollingOutput(cfg api.CfgOutput) (o api.Output, err error) {
 		panic("not support type " + cfg.Type())
 	}
 
+	s, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	window, err := getDedupWindow(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := newSpool(cfg)
+	if err != nil {
+		return nil, err
+	}
 	if cfg["async"] == "true" {
-		r.Output = NewAsyncOutput(w, GetThresholdLvl(cfg["threshold"]),
-			GetQueueSize(cfg["queue_size"]), GetBatchNum(cfg["batch_num"]))
+		r.writerOutput = newAsyncOutput(w, GetThresholdLvl(cfg["threshold"]),
+			GetQueueSize(cfg["queue_size"]), GetBatchNum(cfg["batch_num"]), sp)
 	} else {
-		r.Output = NewBaseOutput(w, GetThresholdLvl(cfg["threshold"]))
+		r.writerOutput = newBaseOutput(w, GetThresholdLvl(cfg["threshold"]))
 	}
+	r.writerOutput.SetSampler(s)
+	r.writerOutput = newDedupOutput(r.writerOutput, window)
 	return r, nil
 }
 
+// SetErrorHandler set the handler of the errors of writing and rolling files
+func (r *rollingOutput) SetErrorHandler(h func(err error)) {
+	r.writerOutput.SetErrorHandler(h)
+	r.rw.rollLock.Lock()
+	r.rw.onError = h
+	r.rw.rollLock.Unlock()
+}
+
 func getMaxSize(str string) int64 {
 	if strings.HasSuffix(str, "K") {
 		size, _ := strconv.Atoi(str)
@@ -195,6 +195,8 @@ type rollingFileWriter struct {
 	filePerm        os.FileMode
 	backPerm        os.FileMode
 	rollLock        sync.Mutex
+	onError         func(err error) // handle the errors not returned by Write, guarded by rollLock
+	errs            []error         // the errors not returned by Write, reported after releasing rollLock
 }
 
 func newRollingFileWriter(fpath string, apath string) *rollingFileWriter {
@@ -436,7 +438,7 @@ func (rw *rollingFileWriter) deleteOldRolls(history []string) error {
 	for i := 0; i < rollsToDelete; i++ {
 		// Try best to delete files without breaking the loop.
 		if err = tryRemoveFile(filepath.Join(rw.currentDirPath, history[i])); err != nil {
-			reportInternalError(err)
+			rw.reportError(err)
 		}
 	}
 
@@ -511,8 +513,24 @@ func (rw *rollingFileWriter) roll() error {
 
 func (rw *rollingFileWriter) Write(bytes []byte) (n int, err error) {
 	rw.rollLock.Lock()
-	defer rw.rollLock.Unlock()
+	n, err = rw.write(bytes)
+	errs, h := rw.errs, rw.onError
+	rw.errs = nil
+	rw.rollLock.Unlock()
+
+	// the handler may write to this writer again
+	for _, e := range errs {
+		if h != nil {
+			h(e)
+		} else {
+			reportInternalError(e)
+		}
+	}
+	return n, err
+}
 
+// write the bytes after rolling if needed, the caller must hold the rollLock
+func (rw *rollingFileWriter) write(bytes []byte) (n int, err error) {
 	if rw.self.needsToRoll() {
 		if err := rw.roll(); err != nil {
 			return 0, err
@@ -531,6 +549,12 @@ func (rw *rollingFileWriter) Write(bytes []byte) (n int, err error) {
 	return n, err
 }
 
+// reportError keep the error to be reported to the handler by Write after releasing the rollLock,
+// the caller must hold the rollLock
+func (rw *rollingFileWriter) reportError(err error) {
+	rw.errs = append(rw.errs, err)
+}
+
 func (rw *rollingFileWriter) Close() error {
 	if rw.currentFile != nil {
 		e := rw.currentFile.Close()
@@ -1,12 +1,15 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 const (
@@ -310,3 +313,54 @@ var rollingfileWriterTests = []*fileWriterTestCase{
 	createRollingSizeFileWriterTestCase([]string{"log.testlog", "log.testlog.1"}, "log.testlog", 10, 1, 2, []string{"log.testlog", "log.testlog.2", "dir/log.testlog.1.gz"}, rollingNameModePostfix, rollingArchiveGzip, true, "dir"),
 	// ====================
 }
+
+// errorRoller reports a error when checking whether to roll at the first time
+type errorRoller struct {
+	*rollingFileWriterSize
+	reported bool
+}
+
+func (r *errorRoller) needsToRoll() bool {
+	if !r.reported {
+		r.reported = true
+		r.reportError(errors.New("roll failed"))
+	}
+	return false
+}
+
+func TestRollingFileWriterReportError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rolling")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rws := &rollingFileWriterSize{newRollingFileWriter(filepath.Join(dir, "log.testlog"), ""), 1024}
+	rws.self = &errorRoller{rollingFileWriterSize: rws}
+	// the handler writing to the writer again is called after releasing the lock
+	var reported []error
+	rws.onError = func(err error) {
+		reported = append(reported, err)
+		_, _ = rws.Write([]byte("status\n"))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		_, _ = rws.Write([]byte("hello\n"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock when reporting the error")
+	}
+	rws.Close()
+
+	if len(reported) != 1 || reported[0].Error() != "roll failed" {
+		t.Errorf("reported errors %v", reported)
+	}
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "log.testlog"))
+	if string(b) != "hello\nstatus\n" {
+		t.Errorf("written %q", b)
+	}
+}
@@ -0,0 +1,450 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	typeRouting = "routing"
+
+	routingTemplatePrefix      = "template."
+	defaultRoutingKey          = "default"
+	defaultRoutingIdleTimeout  = 5 * time.Minute
+	defaultRoutingMaxChildren  = 100
+	routingRouteByField        = "field:"
+	routingRouteByLogger       = "logger"
+	routingRouteByLoggerPrefix = "logger:"
+	routingRouteByLevel        = "level"
+)
+
+// routingChild is a output created from the template for a route key
+type routingChild struct {
+	ready chan struct{} // closed when the output is created or failed to create
+
+	sync.RWMutex // held for reading by the senders and for writing by closing
+	output       api.Output
+	closed       bool
+
+	err      error     // the error of creating the output
+	failures int       // the times of creating the output failed continuously
+	retry    time.Time // the time to create the output again after failed
+	used     int64     // atomic, the unix nano time of the last use
+}
+
+// send the event to the output if it is not closed, return whether sent
+func (c *routingChild) send(e *api.Event) bool {
+	c.RLock()
+	defer c.RUnlock()
+	if c.closed {
+		return false
+	}
+	c.output.Send(e)
+	return true
+}
+
+// flush the output if it buffers events and is not closed
+func (c *routingChild) flush() {
+	c.RLock()
+	defer c.RUnlock()
+	if fo, ok := c.output.(api.FlushOutput); ok && !c.closed {
+		fo.Flush()
+	}
+}
+
+// close the output after it is created and the senders return
+func (c *routingChild) close() {
+	<-c.ready
+	c.Lock()
+	defer c.Unlock()
+	if !c.closed && c.output != nil {
+		c.output.Close()
+	}
+	c.closed = true
+}
+
+// routingOutput dispatches events to the child outputs created from the template by the route key
+type routingOutput struct {
+	name        string
+	t           api.Level // threshold
+	placeholder string    // replaced by the route key in the template values
+	key         func(e *api.Event) string
+	defaultKey  string
+	template    api.CfgOutput
+	creator     api.OutputFuncCreator
+	idleTimeout time.Duration
+	maxChildren int
+
+	sync.Mutex
+	f        api.Formatter
+	h        func(err error)
+	children map[string]*routingChild // key: route key
+	closed   bool
+	stop     chan struct{}
+	wait     sync.WaitGroup
+
+	events  uint64 // atomic
+	dropped uint64 // atomic
+}
+
+// newRoutingCreator return the creator of routing outputs, lookup return the creator of the child outputs
+// and is called when the routing output created:
+//
+//	route_by          the route key: 'field:<key>', 'logger' (full name), 'logger:<N>' (the N-th name segment from 0) or 'level'
+//	route_default     the route key if the field or segment is absent, default is 'default'
+//	template.<key>    the config of the child outputs, '%{<field key>}', '%{logger}' or '%{level}' in the values is
+//	                  replaced by the route key, eg. 'template.file: log/%{tenant}.log'
+//	idle_timeout      close the child not used in the duration, default is 5m
+//	max_children      close the least recently used child if exceeded, default is 100
+func newRoutingCreator(lookup func(stype string) (api.OutputFuncCreator, bool)) api.OutputFuncCreator {
+	return func(cfg api.CfgOutput) (api.Output, error) {
+		o := &routingOutput{
+			name:        cfg.Name(),
+			t:           GetThresholdLvl(cfg["threshold"]),
+			defaultKey:  defaultRoutingKey,
+			template:    make(api.CfgOutput),
+			idleTimeout: defaultRoutingIdleTimeout,
+			maxChildren: defaultRoutingMaxChildren,
+			children:    make(map[string]*routingChild),
+			stop:        make(chan struct{}),
+		}
+		if err := o.parseRouteBy(cfg["route_by"]); err != nil {
+			return nil, err
+		}
+		if s, ok := cfg["route_default"]; ok {
+			o.defaultKey = sanitizeRouteKey(s)
+		}
+		for k, v := range cfg {
+			if strings.HasPrefix(k, routingTemplatePrefix) {
+				o.template[strings.TrimPrefix(k, routingTemplatePrefix)] = v
+			}
+		}
+
+		// the outputs depending on the other outputs of the manager can not be created out of its lock
+		switch o.template.Type() {
+		case typeRouting, typeFailover, typeRing:
+			return nil, fmt.Errorf("%s output can not be the template of output[%s]", o.template.Type(), o.name)
+		}
+		// the children can not share a spool directory
+		if _, ok := o.template["spool_dir"]; ok {
+			return nil, fmt.Errorf("spool_dir can not be set in the template of output[%s]", o.name)
+		}
+		var ok bool
+		if o.creator, ok = lookup(o.template.Type()); !ok {
+			return nil, fmt.Errorf("not find registered output.type[%s] creator for template of output[%s]",
+				o.template.Type(), o.name)
+		}
+		if s, ok := cfg["idle_timeout"]; ok {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid idle_timeout %q of output[%s]", s, o.name)
+			}
+			o.idleTimeout = d
+		}
+		if s, ok := cfg["max_children"]; ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid max_children %q of output[%s]", s, o.name)
+			}
+			o.maxChildren = n
+		}
+
+		o.wait.Add(1)
+		go o.loop()
+		return o, nil
+	}
+}
+
+func (o *routingOutput) parseRouteBy(routeBy string) error {
+	switch {
+	case strings.HasPrefix(routeBy, routingRouteByField) && len(routeBy) > len(routingRouteByField):
+		field := strings.TrimPrefix(routeBy, routingRouteByField)
+		o.placeholder = field
+		o.key = func(e *api.Event) string {
+			if v := e.Ctx.Value(field); v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+	case routeBy == routingRouteByLogger:
+		o.placeholder = routingRouteByLogger
+		o.key = func(e *api.Event) string { return e.Name }
+	case strings.HasPrefix(routeBy, routingRouteByLoggerPrefix):
+		n, err := strconv.Atoi(strings.TrimPrefix(routeBy, routingRouteByLoggerPrefix))
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid route_by %q of output[%s]", routeBy, o.name)
+		}
+		o.placeholder = routingRouteByLogger
+		o.key = func(e *api.Event) string {
+			segs := strings.FieldsFunc(e.Name, func(r rune) bool {
+				return r == packageSepBySlash || r == packageSepByDot
+			})
+			if n < len(segs) {
+				return segs[n]
+			}
+			return ""
+		}
+	case routeBy == routingRouteByLevel:
+		o.placeholder = routingRouteByLevel
+		o.key = func(e *api.Event) string { return strings.ToLower(e.Level.String()) }
+	default:
+		return fmt.Errorf("invalid route_by %q of output[%s]", routeBy, o.name)
+	}
+	return nil
+}
+
+// sanitizeRouteKey replace the characters which are not safe in a file name by '_'
+func sanitizeRouteKey(key string) string {
+	bs := []byte(key)
+	for i, c := range bs {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			bs[i] = '_'
+		}
+	}
+	key = string(bs)
+	if key == "." || key == ".." {
+		key = strings.Repeat("_", len(key))
+	}
+	return key
+}
+
+// Send the event to the child output of its route key, the child is created if not exists.
+// The child is created and sent to without holding the lock, the events of the route key are
+// dropped until the retry time if the child failed to create.
+func (o *routingOutput) Send(e *api.Event) {
+	if e.Level < o.t {
+		atomic.AddUint64(&o.dropped, 1)
+		return
+	}
+	key := o.key(e)
+	if key == "" {
+		key = o.defaultKey
+	} else {
+		key = sanitizeRouteKey(key)
+	}
+
+	for {
+		c, create, evicted := o.getChild(key)
+		if c == nil {
+			return
+		}
+		if evicted != nil {
+			evicted.close()
+		}
+		if create {
+			o.create(key, c)
+		}
+		<-c.ready
+		if c.err != nil {
+			atomic.AddUint64(&o.dropped, 1)
+			return
+		}
+		// the child closed after got is removed, send to the one created again
+		if c.send(e) {
+			atomic.AddUint64(&o.events, 1)
+			return
+		}
+	}
+}
+
+// getChild return the child of the key, nil if the output is closed. A child is added to be created by the caller
+// if not exists or the retry time of the failed one arrives, the least recently used child is evicted if exceeded.
+func (o *routingOutput) getChild(key string) (c *routingChild, create bool, evicted *routingChild) {
+	o.Lock()
+	defer o.Unlock()
+	if o.closed {
+		return nil, false, nil
+	}
+
+	now := time.Now()
+	c, ok := o.children[key]
+	if !ok || c.err != nil && !now.Before(c.retry) {
+		var failures int
+		if ok {
+			failures = c.failures
+		} else if len(o.children) >= o.maxChildren {
+			evicted = o.removeLRU()
+		}
+		c = &routingChild{ready: make(chan struct{}), failures: failures}
+		o.children[key] = c
+		create = true
+	}
+	atomic.StoreInt64(&c.used, now.UnixNano())
+	return c, create, evicted
+}
+
+// create the output of the child from the template, the error is reported by the error handler
+func (o *routingOutput) create(key string, c *routingChild) {
+	placeholder := "%{" + o.placeholder + "}"
+	cfg := make(api.CfgOutput, len(o.template)+1)
+	for k, v := range o.template {
+		cfg[k] = strings.Replace(v, placeholder, key, -1)
+	}
+	cfg["name"] = o.name + "/" + key
+	op, err := o.creator(cfg)
+
+	o.Lock()
+	if err == nil {
+		if o.f != nil {
+			op.SetFormatter(o.f)
+		}
+		if eo, ok := op.(errorReportOutput); ok && o.h != nil {
+			eo.SetErrorHandler(o.h)
+		}
+		c.output = op
+	} else {
+		c.err = fmt.Errorf("failed to create output[%s]: %v", cfg.Name(), err)
+		c.retry = time.Now().Add(routingRetryInterval(c.failures))
+		c.failures++
+	}
+	h := o.h
+	o.Unlock()
+	close(c.ready)
+
+	if err != nil {
+		if h != nil {
+			h(c.err)
+		} else {
+			reportInternalError(c.err)
+		}
+	}
+}
+
+// routingRetryInterval return the interval to create a child again after it failed the times,
+// it starts from 1s and doubles up to 1m
+func routingRetryInterval(failures int) time.Duration {
+	if failures >= 6 {
+		return time.Minute
+	}
+	return time.Second << uint(failures)
+}
+
+// removeLRU remove the least recently used child and return it, the caller must hold the lock
+func (o *routingOutput) removeLRU() *routingChild {
+	var lru string
+	for k, c := range o.children {
+		if lru == "" || atomic.LoadInt64(&c.used) < atomic.LoadInt64(&o.children[lru].used) {
+			lru = k
+		}
+	}
+	c := o.children[lru]
+	delete(o.children, lru)
+	return c
+}
+
+// closeIdle close the children not used in the idle timeout
+func (o *routingOutput) closeIdle() {
+	var idle []*routingChild
+	o.Lock()
+	now := time.Now().UnixNano()
+	for k, c := range o.children {
+		if now-atomic.LoadInt64(&c.used) >= int64(o.idleTimeout) {
+			idle = append(idle, c)
+			delete(o.children, k)
+		}
+	}
+	o.Unlock()
+
+	for _, c := range idle {
+		c.close()
+	}
+}
+
+func (o *routingOutput) loop() {
+	defer o.wait.Done()
+	tick := time.NewTicker(o.idleTimeout / 2)
+	defer tick.Stop()
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-tick.C:
+			o.closeIdle()
+		}
+	}
+}
+
+// SetFormatter set the formatter for all child outputs
+func (o *routingOutput) SetFormatter(f api.Formatter) {
+	o.Lock()
+	defer o.Unlock()
+	o.f = f
+	// the children being created are set by create
+	for _, c := range o.children {
+		if c.output != nil {
+			c.output.SetFormatter(f)
+		}
+	}
+}
+
+// SetErrorHandler set the handler of the errors of writing the children
+func (o *routingOutput) SetErrorHandler(h func(err error)) {
+	o.Lock()
+	defer o.Unlock()
+	o.h = h
+	for _, c := range o.children {
+		if eo, ok := c.output.(errorReportOutput); ok {
+			eo.SetErrorHandler(h)
+		}
+	}
+}
+
+// CallerInfoFlag return the caller info flag by formatter
+func (o *routingOutput) CallerInfoFlag() int {
+	o.Lock()
+	defer o.Unlock()
+	if o.f != nil {
+		return o.f.CallerInfoFlag()
+	}
+	return ciNoneFlog
+}
+
+// Stats return the events dispatched to the children and the ones dropped by the threshold or the children failed to create
+func (o *routingOutput) Stats() api.OutputStats {
+	return api.OutputStats{
+		Events:  atomic.LoadUint64(&o.events),
+		Dropped: atomic.LoadUint64(&o.dropped),
+	}
+}
+
+// Flush all children which buffer events
+func (o *routingOutput) Flush() {
+	o.Lock()
+	children := make([]*routingChild, 0, len(o.children))
+	for _, c := range o.children {
+		if c.output != nil {
+			children = append(children, c)
+		}
+	}
+	o.Unlock()
+
+	for _, c := range children {
+		c.flush()
+	}
+}
+
+// Close all children and stop closing the idle children
+func (o *routingOutput) Close() {
+	o.Lock()
+	if o.closed {
+		o.Unlock()
+		return
+	}
+	o.closed = true
+	children := o.children
+	o.children = make(map[string]*routingChild)
+	o.Unlock()
+
+	for _, c := range children {
+		c.close()
+	}
+
+	close(o.stop)
+	o.wait.Wait()
+}
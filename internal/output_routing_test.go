@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestRoutingOutput(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"r1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{tenant}|%{msg}\n"}},
+		Outputs: []api.CfgOutput{{"type": "routing", "name": "r1", "format": "f1", "route_by": "field:tenant",
+			"template.type": "memory", "template.file": "log/%{tenant}.log", "max_children": "2"}},
+	})
+	assert.NoError(t, err)
+	log := ctx.GetLogger("a/b")
+	tenant := func(v string) api.Writer { return log.WithFields(api.Field{Key: "tenant", Value: v}) }
+
+	tenant("t1").Info("1")
+	tenant("t2").Info("2")
+	tenant("t1").Info("3")
+	log.Info("4")
+	tenant("../t3").Info("5")
+
+	ro := ctx.Manager().(*defManager).outputs["r1"].(*routingOutput)
+	child := func(key string) string {
+		c, ok := ro.children[key]
+		if !ok {
+			return ""
+		}
+		return c.output.(*memoryOutput).String()
+	}
+	// t1 is closed as the least recently used one after 'default' created
+	assert.Len(t, ro.children, 2)
+	assert.Equal(t, "", child("t1"))
+	assert.Equal(t, "<nil>|4\n", child("default"))
+	assert.Equal(t, "../t3|5\n", child(".._t3"))
+	assert.Equal(t, api.OutputStats{Events: 5}, ro.Stats())
+	ctx.Close()
+	assert.Len(t, ro.children, 0)
+}
+
+func TestRoutingOutputConfig(t *testing.T) {
+	var names []string
+	lookup := func(stype string) (api.OutputFuncCreator, bool) {
+		return func(cfg api.CfgOutput) (api.Output, error) {
+			names = append(names, cfg.Name()+":"+cfg["file"])
+			return NewMemoryOutput(cfg)
+		}, stype == typeMemory
+	}
+	create := newRoutingCreator(lookup)
+	for _, cfg := range []api.CfgOutput{
+		{"route_by": "x", "template.type": "memory"},
+		{"route_by": "logger:x", "template.type": "memory"},
+		{"route_by": "level", "template.type": "file"},
+		{"route_by": "level", "template.type": "memory", "idle_timeout": "0"},
+		{"route_by": "level", "template.type": "memory", "max_children": "-1"},
+		{"route_by": "level", "template.type": "routing"},
+		{"route_by": "level", "template.type": "memory", "template.spool_dir": "log/spool"},
+	} {
+		_, err := create(cfg)
+		assert.Error(t, err, cfg)
+	}
+
+	op, err := create(api.CfgOutput{"name": "r", "route_by": "logger:1", "template.type": "memory",
+		"template.file": "log/%{logger}.log", "idle_timeout": "20ms"})
+	assert.NoError(t, err)
+	ro := op.(*routingOutput)
+	ro.Send(&api.Event{Name: "a/b.c", Level: api.Info, Ctx: context.Background()})
+	ro.Send(&api.Event{Name: "a", Level: api.Info, Ctx: context.Background()})
+	ro.Lock()
+	assert.Len(t, ro.children, 2)
+	assert.Contains(t, ro.children, "b")
+	assert.Contains(t, ro.children, "default")
+	assert.Equal(t, []string{"r/b:log/b.log", "r/default:log/default.log"}, names)
+	ro.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+	ro.Lock()
+	assert.Len(t, ro.children, 0)
+	ro.Unlock()
+	ro.Close()
+
+	assert.Equal(t, "_", sanitizeRouteKey("."))
+	assert.Equal(t, "a_b_c", sanitizeRouteKey("a/b\\c"))
+}
+
+func TestRoutingOutputCreateFailed(t *testing.T) {
+	var created int
+	lookup := func(stype string) (api.OutputFuncCreator, bool) {
+		return func(cfg api.CfgOutput) (api.Output, error) {
+			created++
+			return nil, errors.New("failed")
+		}, true
+	}
+	op, err := newRoutingCreator(lookup)(api.CfgOutput{"name": "r", "route_by": "level", "template.type": "memory"})
+	assert.NoError(t, err)
+	ro := op.(*routingOutput)
+	defer ro.Close()
+	var errs []string
+	ro.SetErrorHandler(func(err error) { errs = append(errs, err.Error()) })
+
+	// the failure is cached until the retry time and reported once
+	for i := 0; i < 3; i++ {
+		ro.Send(&api.Event{Level: api.Info, Ctx: context.Background()})
+	}
+	assert.Equal(t, 1, created)
+	assert.Equal(t, []string{"failed to create output[r/info]: failed"}, errs)
+	assert.Equal(t, api.OutputStats{Dropped: 3}, ro.Stats())
+
+	// created again after the retry time, the interval is doubled
+	ro.Lock()
+	c := ro.children["info"]
+	c.retry = time.Now()
+	ro.Unlock()
+	ro.Send(&api.Event{Level: api.Info, Ctx: context.Background()})
+	assert.Equal(t, 2, created)
+	assert.Len(t, errs, 2)
+	ro.Lock()
+	assert.Equal(t, 2, ro.children["info"].failures)
+	ro.Unlock()
+	assert.Equal(t, time.Second, routingRetryInterval(0))
+	assert.Equal(t, 4*time.Second, routingRetryInterval(2))
+	assert.Equal(t, time.Minute, routingRetryInterval(10))
+}
+
+func TestRoutingOutputTemplateType(t *testing.T) {
+	// the failover and ring outputs look up the outputs of the manager, they can not be the template
+	for _, typ := range []string{"failover", "ring"} {
+		ctx, err := NewLoggerContext(&api.Config{
+			Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"r1"}}},
+			Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": "%{msg}\n"}},
+			Outputs: []api.CfgOutput{
+				{"type": "memory", "name": "m1", "format": "f1"},
+				{"type": "memory", "name": "m2", "format": "f1"},
+				{"type": "routing", "name": "r1", "format": "f1", "route_by": "level", "template.type": typ,
+					"template.primary": "m1", "template.secondaries": "m2", "template.target": "m1"},
+			},
+		})
+		assert.NoError(t, err)
+		_, _, err = ctx.Manager().GetLoggerOutputs("root")
+		assert.EqualError(t, err, typ+" output can not be the template of output[r1]")
+		ctx.Close()
+	}
+}
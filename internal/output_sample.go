@@ -0,0 +1,212 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	summaryLoggerName      = "log4g"
+	defaultSummaryInterval = time.Minute
+)
+
+type summaryKey struct{}
+
+type sampleKey struct {
+	lvl    api.Level
+	format string
+}
+
+// sampler suppresses events by sampling and rate limiting, and reports the number of
+// suppressed events by a summary event periodically.
+type sampler struct {
+	first      uint64        // the events logged in each second for each key
+	thereafter uint64        // 1 in thereafter events logged after the first ones
+	rate       float64       // tokens added per second, 0 means no rate limiting
+	burst      float64       // capacity of the token bucket
+	interval   time.Duration // interval of the summary event
+
+	sync.Mutex
+	sec    int64 // the second of counts
+	counts map[sampleKey]uint64
+	tokens float64
+	last   time.Time // the time of the last tokens refilled
+
+	suppressed uint64 // atomic, the suppressed events since the last summary
+	send       func(e *api.Event)
+	stop       chan struct{}
+	wait       sync.WaitGroup
+}
+
+// newSampler return a sampler by the output configuration, nil if neither sampling nor rate limiting configured:
+//
+//	sample_first       the first N events per second with the same level and message format are logged
+//	sample_thereafter  then 1 in M events are logged, 0 means none
+//	rate_limit         the events per second allowed by the token bucket
+//	rate_burst         the capacity of the token bucket, default is rate_limit
+//	summary_interval   the interval of the summary event, default is 1m
+func newSampler(cfg api.CfgOutput) (s *sampler, err error) {
+	_, sampling := cfg["sample_first"]
+	_, thereafter := cfg["sample_thereafter"]
+	_, limiting := cfg["rate_limit"]
+	if !sampling && !thereafter && !limiting {
+		return nil, nil
+	}
+
+	s = &sampler{interval: defaultSummaryInterval, counts: make(map[sampleKey]uint64)}
+	if sampling || thereafter {
+		if s.first, err = parseCfgUint(cfg, "sample_first"); err != nil {
+			return nil, err
+		}
+		if s.thereafter, err = parseCfgUint(cfg, "sample_thereafter"); err != nil {
+			return nil, err
+		}
+	}
+	if limiting {
+		if s.rate, err = strconv.ParseFloat(cfg["rate_limit"], 64); err != nil || s.rate <= 0 {
+			return nil, fmt.Errorf("invalid rate_limit %q of output[%s]", cfg["rate_limit"], cfg.Name())
+		}
+		s.burst = s.rate
+		if str, ok := cfg["rate_burst"]; ok {
+			if s.burst, err = strconv.ParseFloat(str, 64); err != nil || s.burst < 1 {
+				return nil, fmt.Errorf("invalid rate_burst %q of output[%s]", str, cfg.Name())
+			}
+		}
+		s.tokens = s.burst
+	}
+	if str, ok := cfg["summary_interval"]; ok {
+		if s.interval, err = time.ParseDuration(str); err != nil || s.interval <= 0 {
+			return nil, fmt.Errorf("invalid summary_interval %q of output[%s]", str, cfg.Name())
+		}
+	}
+	return s, nil
+}
+
+func parseCfgUint(cfg api.CfgOutput, key string) (uint64, error) {
+	str, ok := cfg[key]
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(str, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q of output[%s]", key, str, cfg.Name())
+	}
+	return n, nil
+}
+
+// allow return whether the event is logged, the summary event is always allowed
+func (s *sampler) allow(e *api.Event) bool {
+	if isSummary(e) {
+		return true
+	}
+
+	s.Lock()
+	ok := s.sample(e) && s.limit(e.Time)
+	s.Unlock()
+	if !ok {
+		atomic.AddUint64(&s.suppressed, 1)
+	}
+	return ok
+}
+
+// sample return whether the event is sampled, the caller must hold the lock
+func (s *sampler) sample(e *api.Event) bool {
+	if s.first == 0 && s.thereafter == 0 {
+		return true
+	}
+	if sec := e.Time.Unix(); sec != s.sec {
+		s.sec = sec
+		s.counts = make(map[sampleKey]uint64)
+	}
+
+	key := sampleKey{lvl: e.Level, format: e.Format}
+	if key.format == "" {
+		key.format = e.Message()
+	}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter != 0 && (n-s.first)%s.thereafter == 0
+}
+
+// limit return whether a token is taken from the bucket, the caller must hold the lock
+func (s *sampler) limit(now time.Time) bool {
+	if s.rate == 0 {
+		return true
+	}
+	if !s.last.IsZero() && now.After(s.last) {
+		s.tokens += now.Sub(s.last).Seconds() * s.rate
+		if s.tokens > s.burst {
+			s.tokens = s.burst
+		}
+	}
+	if s.last.IsZero() || now.After(s.last) {
+		s.last = now
+	}
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// start send the summary events by send periodically
+func (s *sampler) start(send func(e *api.Event)) {
+	s.send = send
+	s.stop = make(chan struct{})
+	s.wait.Add(1)
+	go func() {
+		defer s.wait.Done()
+		tick := time.NewTicker(s.interval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-tick.C:
+				s.summary()
+			}
+		}
+	}()
+}
+
+// close stop sending the summary events periodically and send the last one
+func (s *sampler) close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.wait.Wait()
+	s.stop = nil
+	s.summary()
+}
+
+// summary send a summary event if any events suppressed since the last one
+func (s *sampler) summary() {
+	n := atomic.SwapUint64(&s.suppressed, 0)
+	if n == 0 {
+		return
+	}
+	s.send(&api.Event{
+		Time:      time.Now(),
+		Name:      summaryLoggerName,
+		Level:     api.Warn,
+		Format:    "suppressed %d events by sampling or rate limiting",
+		Arguments: []interface{}{n},
+		Ctx: api.WithCaller(context.WithValue(context.Background(), summaryKey{}, true),
+			runtime.Frame{File: "???", Function: summaryLoggerName + ".summary"}),
+	})
+}
+
+func isSummary(e *api.Event) bool {
+	return e.Ctx != nil && e.Ctx.Value(summaryKey{}) != nil
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestSampler(t *testing.T) {
+	s, err := newSampler(api.CfgOutput{"name": "o1"})
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	for _, cfg := range []api.CfgOutput{
+		{"sample_first": "x"}, {"sample_thereafter": "-1"}, {"rate_limit": "0"},
+		{"rate_limit": "1", "rate_burst": "0"}, {"rate_limit": "1", "summary_interval": "1"},
+	} {
+		_, err = newSampler(cfg)
+		assert.Error(t, err, cfg)
+	}
+
+	s, err = newSampler(api.CfgOutput{"sample_first": "2", "sample_thereafter": "3"})
+	assert.NoError(t, err)
+	now := time.Unix(100, 0)
+	var allowed []int
+	for i := 1; i <= 10; i++ {
+		if s.allow(&api.Event{Time: now, Level: api.Warn, Format: "hot %d", Ctx: context.Background()}) {
+			allowed = append(allowed, i)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 5, 8}, allowed)
+	// another key and the next second are sampled separately
+	assert.True(t, s.allow(&api.Event{Time: now, Level: api.Warn, Format: "other", Ctx: context.Background()}))
+	assert.True(t, s.allow(&api.Event{Time: now.Add(time.Second), Level: api.Warn, Format: "hot %d", Ctx: context.Background()}))
+	assert.Equal(t, uint64(6), s.suppressed)
+
+	s, err = newSampler(api.CfgOutput{"rate_limit": "2", "rate_burst": "3"})
+	assert.NoError(t, err)
+	allowed = allowed[:0]
+	for i := 0; i < 8; i++ {
+		// 4 events per second
+		evt := &api.Event{Time: now.Add(time.Duration(i) * 250 * time.Millisecond), Format: "x", Ctx: context.Background()}
+		if s.allow(evt) {
+			allowed = append(allowed, i)
+		}
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 6}, allowed)
+}
+
+func TestSamplerSummary(t *testing.T) {
+	var buf bytes.Buffer
+	op := newBaseOutput(&buf, api.Info)
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{module}|%{lvl}|%{msg}|%{shortfunc}\n"})
+	op.SetFormatter(f)
+	s, _ := newSampler(api.CfgOutput{"sample_first": "1", "summary_interval": "1h"})
+	op.SetSampler(s)
+
+	for i := 0; i < 3; i++ {
+		op.Send(&api.Event{Time: time.Now(), Name: "m", Level: api.Info, Format: "hot", Ctx: context.Background(), CallDepth: 1})
+	}
+	op.Close()
+	assert.Regexp(t, `^m\|INF\|hot\|\w+\nlog4g\|WRN\|suppressed 2 events by sampling or rate limiting\|summary\n$`, buf.String())
+	assert.Equal(t, uint64(2), op.Stats().Dropped)
+
+	buf.Reset()
+	aop := newAsyncOutput(&buf, api.Info, 10, 10, nil)
+	aop.SetFormatter(f)
+	s, _ = newSampler(api.CfgOutput{"rate_limit": "1", "summary_interval": "10ms"})
+	aop.SetSampler(s)
+	for i := 0; i < 3; i++ {
+		aop.Send(&api.Event{Time: time.Now(), Name: "m", Level: api.Info, Format: "hot", Ctx: context.Background(), CallDepth: 1})
+	}
+	time.Sleep(100 * time.Millisecond)
+	aop.Flush()
+	assert.Contains(t, buf.String(), "log4g|WRN|suppressed 2 events by sampling or rate limiting|summary\n")
+	aop.Close()
+}
+
+func TestSamplerMemoryOutput(t *testing.T) {
+	op, err := NewMemoryOutput(api.CfgOutput{"type": "memory", "name": "m1", "sample_first": "1", "summary_interval": "1h"})
+	assert.NoError(t, err)
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{msg}\n"})
+	op.SetFormatter(f)
+	for i := 0; i < 3; i++ {
+		op.Send(&api.Event{Time: time.Now(), Level: api.Info, Format: "hot", Ctx: context.Background()})
+	}
+
+	// the summary goroutine is stopped and the last summary is sent by Close
+	mo := op.(*memoryOutput)
+	s := mo.writerOutput.(*baseOutput).s
+	op.Close()
+	assert.Nil(t, s.stop)
+	assert.Equal(t, "hot\nsuppressed 2 events by sampling or rate limiting\n", mo.String())
+
+	_, err = NewMemoryOutput(api.CfgOutput{"type": "memory", "name": "m1", "rate_limit": "x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,444 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	spoolSuffix             = ".spool"
+	spoolLockFile           = "spool.lock"
+	spoolHeaderSize         = 8 // length and number of events of a record, both uint32
+	defaultSpoolMaxSize     = 100 * 1024 * 1024
+	defaultSpoolSegmentSize = 4 * 1024 * 1024
+)
+
+// spool is the write-ahead queue of a async output, the events are appended to segment files under
+// a directory before written, and replayed in order to the writer. A segment is removed or truncated
+// when all its records are written, the records of a segment partly written before a crash are
+// replayed again by the next run, so the events are written at least once.
+// The batches of events are appended and replayed by the loop of the output.
+type spool struct {
+	dir     string
+	maxSize int64 // the max size of all segments
+	segSize int64 // a new segment is created when the last one exceeds it
+	lock    *os.File
+	report  func(err error) // report the errors not returned to the output, print them to stderr if nil
+
+	sync.Mutex
+	segs         []spoolSegment // sorted by seq
+	size         int64          // the size of all segments
+	offset       int64          // the offset of the first segment replayed
+	offsetEvents int            // the events of the records before the offset
+	f            *os.File       // the last segment opened for appending, nil if not opened
+	closed       bool
+}
+
+type spoolSegment struct {
+	seq    uint64
+	size   int64
+	events int // the events of the whole records, counted when loaded and appended
+}
+
+// newSpool return the spool configured by spool_dir, spool_max_size and spool_segment_size,
+// nil if spool_dir is not set
+func newSpool(cfg api.CfgOutput) (*spool, error) {
+	dir := cfg["spool_dir"]
+	if dir == "" {
+		return nil, nil
+	}
+	if cfg["async"] != "true" {
+		return nil, fmt.Errorf("output %s: spool_dir requires async output", cfg.Name())
+	}
+
+	maxSize := getSpoolSize(cfg["spool_max_size"], defaultSpoolMaxSize)
+	segSize := getSpoolSize(cfg["spool_segment_size"], defaultSpoolSegmentSize)
+	if segSize > maxSize {
+		segSize = maxSize
+	}
+	return openSpool(dir, maxSize, segSize)
+}
+
+// getSpoolSize parse the size in bytes or with the K, M or G suffix, def if empty or invalid
+func getSpoolSize(str string, def int64) int64 {
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(str, "K"):
+		unit = 1024
+	case strings.HasSuffix(str, "M"):
+		unit = 1024 * 1024
+	case strings.HasSuffix(str, "G"):
+		unit = 1024 * 1024 * 1024
+	}
+	if unit > 1 {
+		str = str[:len(str)-1]
+	}
+	size, _ := strconv.ParseInt(str, 10, 64)
+	if size <= 0 {
+		return def
+	}
+	return size * unit
+}
+
+// openSpool create the directory if not exists, lock it and load the segments left by the last run,
+// it fails if the directory is locked by another spool
+func openSpool(dir string, maxSize, segSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, defaultDirectoryPermissions); err != nil {
+		return nil, err
+	}
+	lock, err := lockSpoolDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+
+	s := &spool{dir: dir, maxSize: maxSize, segSize: segSize, lock: lock}
+	for _, fi := range fis {
+		if !isRegular(fi.Mode()) || !strings.HasSuffix(fi.Name(), spoolSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(fi.Name(), spoolSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		events := countSpoolEvents(filepath.Join(dir, fi.Name()), fi.Size(), maxSize)
+		s.segs = append(s.segs, spoolSegment{seq: seq, size: fi.Size(), events: events})
+		s.size += fi.Size()
+	}
+	sort.Slice(s.segs, func(i, j int) bool { return s.segs[i].seq < s.segs[j].seq })
+	return s, nil
+}
+
+func (s *spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016d%s", seq, spoolSuffix))
+}
+
+// empty return whether all records are replayed
+func (s *spool) empty() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.size == 0 || len(s.segs) == 1 && s.offset == s.segs[0].size
+}
+
+// reportError report the error by the handler of the output owning the spool
+func (s *spool) reportError(err error) {
+	if s.report != nil {
+		s.report(err)
+	} else {
+		reportInternalError(err)
+	}
+}
+
+var errSpoolClosed = errors.New("spool closed")
+
+// append a record of the events to the last segment, the oldest segments are removed to keep the max size.
+// It returns the number of events dropped by removing segments or the record itself if too large.
+func (s *spool) append(b []byte, events int) (dropped int, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return events, errSpoolClosed
+	}
+
+	n := int64(spoolHeaderSize + len(b))
+	if n > s.maxSize {
+		return events, nil
+	}
+	for len(s.segs) != 0 && s.size+n > s.maxSize {
+		dropped += s.removeFirst()
+	}
+
+	if s.f == nil || s.segs[len(s.segs)-1].size >= s.segSize {
+		if err = s.create(); err != nil {
+			return dropped, err
+		}
+	}
+
+	last := &s.segs[len(s.segs)-1]
+	buf := make([]byte, n)
+	binary.BigEndian.PutUint32(buf, uint32(len(b)))
+	binary.BigEndian.PutUint32(buf[4:], uint32(events))
+	copy(buf[spoolHeaderSize:], b)
+	if _, err = s.f.Write(buf); err != nil {
+		// discard the partial record, the next one is appended after the last whole one
+		_ = s.f.Truncate(last.size)
+		_, _ = s.f.Seek(last.size, io.SeekStart)
+		return dropped, err
+	}
+	last.size += n
+	last.events += events
+	s.size += n
+	return dropped, nil
+}
+
+// create a new segment after the last one and open it for appending, the caller must hold the lock
+func (s *spool) create() error {
+	var seq uint64
+	if len(s.segs) != 0 {
+		seq = s.segs[len(s.segs)-1].seq + 1
+	}
+	// the directory is locked, a existing file is left by a crash before it is loaded
+	f, err := os.OpenFile(s.path(seq), os.O_WRONLY|os.O_CREATE|os.O_EXCL, defaultFilePermissions)
+	if err != nil {
+		return err
+	}
+	s.closeFile()
+	s.f = f
+	s.segs = append(s.segs, spoolSegment{seq: seq})
+	return nil
+}
+
+// replay write the records to w in order until all replayed or failed to write, the records are
+// written in batches of at most batchNum events. It returns the number of events and bytes written.
+func (s *spool) replay(w io.Writer, batchNum int) (events int, bytes int, err error) {
+	for {
+		s.Lock()
+		if len(s.segs) == 0 {
+			s.Unlock()
+			return
+		}
+		seg, offset := s.segs[0], s.offset
+		s.Unlock()
+
+		var e, n int
+		if offset < seg.size {
+			e, n, err = s.replaySegment(w, seg, offset, batchNum)
+			events += e
+			bytes += n
+			if err != nil {
+				return
+			}
+		}
+		if !s.replayed(seg) {
+			return
+		}
+	}
+}
+
+// replaySegment write the records of the segment from the offset to its size, the offset is advanced
+// by each batch written unless the segment is removed by appending
+func (s *spool) replaySegment(w io.Writer, seg spoolSegment, offset int64, batchNum int) (events int, bytes int, err error) {
+	f, err := os.Open(s.path(seg.seq))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	defer f.Close()
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(io.LimitReader(f, seg.size-offset))
+	header := make([]byte, spoolHeaderSize)
+	pos := offset // the offset of the next record
+	var batch []byte
+	var batchEvents int
+	var batchSize int64
+	write := func() bool {
+		if batchEvents == 0 {
+			return true
+		}
+		var n int
+		if n, err = w.Write(batch); err == nil && n != len(batch) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return false
+		}
+		events += batchEvents
+		bytes += n
+		written := batchEvents
+		batch, batchEvents = batch[:0], 0
+		return s.advance(seg.seq, &offset, batchSize, written)
+	}
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			// io.ErrUnexpectedEOF means a partial record written before a crash, skip it
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+			write()
+			return
+		}
+		size := spoolBodySize(header, seg.size-pos-spoolHeaderSize, s.maxSize)
+		if size < 0 {
+			// skip the rest of the corrupt segment after the records before written
+			if write() {
+				s.reportError(fmt.Errorf("skip the corrupt records of spool segment %s from offset %d", f.Name(), pos))
+				s.skip(seg, offset)
+			}
+			return
+		}
+		b := make([]byte, size)
+		if _, err = io.ReadFull(r, b); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = nil
+			}
+			write()
+			return
+		}
+		if batchEvents == 0 {
+			batchSize = 0
+		}
+		batch = append(batch, b...)
+		batchEvents += int(binary.BigEndian.Uint32(header[4:]))
+		batchSize += int64(spoolHeaderSize + len(b))
+		pos += int64(spoolHeaderSize + len(b))
+		if batchEvents >= batchNum && !write() {
+			return
+		}
+	}
+}
+
+// advance the offset of the first segment by n after the records of the events written, return false
+// if the segment is removed by appending
+func (s *spool) advance(seq uint64, offset *int64, n int64, events int) bool {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.segs) == 0 || s.segs[0].seq != seq || s.offset != *offset {
+		return false
+	}
+	s.offset += n
+	s.offsetEvents += events
+	*offset = s.offset
+	return true
+}
+
+// skip the records of the first segment from the offset to the size of seg, unless it is removed by appending
+func (s *spool) skip(seg spoolSegment, offset int64) {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.segs) == 0 || s.segs[0].seq != seg.seq || s.offset != offset {
+		return
+	}
+	s.offset, s.offsetEvents = seg.size, seg.events
+}
+
+// replayed remove the segment if all its records written, the segment opened for appending is truncated
+// instead. It returns true if another segment or the records appended after replaying can be replayed.
+func (s *spool) replayed(seg spoolSegment) bool {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.segs) == 0 || s.segs[0].seq != seg.seq {
+		return true
+	}
+	first := &s.segs[0]
+	if len(s.segs) != 1 || s.f == nil {
+		// not appended any more, the partial record written before a crash is discarded
+		s.removeFirst()
+		return true
+	}
+	if s.offset < first.size {
+		// appended after replaying started
+		return first.size != seg.size
+	}
+	if err := s.f.Truncate(0); err != nil {
+		s.reportError(err)
+		return false
+	}
+	_, _ = s.f.Seek(0, io.SeekStart)
+	s.size -= first.size
+	first.size, first.events = 0, 0
+	s.offset, s.offsetEvents = 0, 0
+	return false
+}
+
+// removeFirst remove the first segment, it returns the number of events not replayed in it,
+// the caller must hold the lock
+func (s *spool) removeFirst() int {
+	seg := s.segs[0]
+	if len(s.segs) == 1 {
+		s.closeFile()
+	}
+	events := seg.events - s.offsetEvents
+	if err := os.Remove(s.path(seg.seq)); err != nil && !os.IsNotExist(err) {
+		s.reportError(err)
+	}
+	s.segs = s.segs[1:]
+	s.size -= seg.size
+	s.offset, s.offsetEvents = 0, 0
+	return events
+}
+
+// spoolBodySize return the size of the record body by the header, -1 if it is larger than rest,
+// the bytes after the header in the segment, or than the max size of the spool, which means the
+// segment is corrupt or truncated
+func spoolBodySize(header []byte, rest int64, maxSize int64) int64 {
+	n := int64(binary.BigEndian.Uint32(header))
+	if n > rest || n > maxSize-spoolHeaderSize {
+		return -1
+	}
+	return n
+}
+
+// countSpoolEvents return the number of events of the whole records in a segment file of the size,
+// the records after a corrupt one are not counted. It is called when the segment left by the last run
+// is loaded, the events of the segments appended are counted in memory.
+func countSpoolEvents(path string, size int64, maxSize int64) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	header := make([]byte, spoolHeaderSize)
+	events := 0
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			return events
+		}
+		offset += spoolHeaderSize
+		n := spoolBodySize(header, size-offset, maxSize)
+		if n < 0 {
+			return events
+		}
+		if _, err = r.Discard(int(n)); err != nil {
+			return events
+		}
+		offset += n
+		events += int(binary.BigEndian.Uint32(header[4:]))
+	}
+}
+
+func (s *spool) closeFile() {
+	if s.f != nil {
+		s.f.Close()
+		s.f = nil
+	}
+}
+
+// close the segment opened for appending and unlock the directory, the segments not replayed are kept
+// for the next run, the last one is removed if empty
+func (s *spool) close() {
+	s.Lock()
+	defer s.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.f != nil && len(s.segs) == 1 && s.segs[0].size == 0 {
+		s.removeFirst()
+	}
+	s.closeFile()
+	s.lock.Close()
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func spoolFiles(dir string) []string {
+	fs, _ := filepath.Glob(filepath.Join(dir, "*"+spoolSuffix))
+	for i := range fs {
+		fs[i] = filepath.Base(fs[i])
+	}
+	return fs
+}
+
+func TestSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// each record is 8 bytes header and 2 bytes batch
+	s, err := openSpool(dir, 30, 20)
+	assert.NoError(t, err)
+	assert.True(t, s.empty())
+	// the directory is locked by one spool only
+	_, err = openSpool(dir, 30, 20)
+	assert.Error(t, err)
+	for _, b := range []string{"a|", "b|", "c|"} {
+		dropped, err := s.append([]byte(b), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, dropped)
+	}
+	assert.Equal(t, []string{"0000000000000000.spool", "0000000000000001.spool"}, spoolFiles(dir))
+
+	// the first segment is removed to keep the max size
+	dropped, err := s.append([]byte("d|"), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, dropped)
+	dropped, _ = s.append(make([]byte, 30), 3)
+	assert.Equal(t, 3, dropped)
+
+	// the segments left are loaded by the next run, and replayed until failed to write
+	s.close()
+	s, err = openSpool(dir, 30, 20)
+	assert.NoError(t, err)
+	w := &switchWriter{broken: 1}
+	_, _, err = s.replay(w, 10)
+	assert.Error(t, err)
+	atomic.StoreInt32(&w.broken, 0)
+	events, n, err := s.replay(w, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, events)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, "c|d|", w.String())
+	assert.True(t, s.empty())
+	assert.Empty(t, spoolFiles(dir))
+
+	// the segment appended is truncated after replayed, and removed by closing if empty
+	for _, b := range []string{"e|", "f|"} {
+		_, err = s.append([]byte(b), 1)
+		assert.NoError(t, err)
+	}
+	w.Reset()
+	events, _, err = s.replay(w, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, events)
+	assert.Equal(t, "e|f|", w.String())
+	assert.True(t, s.empty())
+	assert.Equal(t, []string{"0000000000000000.spool"}, spoolFiles(dir))
+	s.close()
+	assert.Empty(t, spoolFiles(dir))
+	_, err = s.append([]byte("g|"), 1)
+	assert.Equal(t, errSpoolClosed, err)
+}
+
+func TestSpoolCorrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := openSpool(dir, 100, 100)
+	assert.NoError(t, err)
+	for _, b := range []string{"a|", "b|"} {
+		_, err = s.append([]byte(b), 1)
+		assert.NoError(t, err)
+	}
+	s.close()
+
+	// the length of the second record is larger than the rest of the segment
+	path := filepath.Join(dir, "0000000000000000.spool")
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	assert.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 10)
+	assert.NoError(t, err)
+	f.Close()
+	assert.Equal(t, 1, countSpoolEvents(path, 20, 100))
+
+	s, err = openSpool(dir, 100, 100)
+	assert.NoError(t, err)
+	var reported []error
+	s.report = func(err error) { reported = append(reported, err) }
+	w := &switchWriter{}
+	events, _, err := s.replay(w, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, events)
+	assert.Len(t, reported, 1)
+	assert.Equal(t, "a|", w.String())
+	assert.True(t, s.empty())
+	assert.Empty(t, spoolFiles(dir))
+	s.close()
+}
+
+func TestAsyncOutputSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f, _ := NewTextFormatter(api.CfgFormat{"type": "text", "name": "f1", "layout": "%{msg}|"})
+	cfg := api.CfgOutput{"type": "console", "name": "o1", "async": "true", "spool_dir": dir}
+	send := func(o writerOutput, msg string) {
+		o.Send(&api.Event{Format: msg, Level: api.Info, Ctx: context.Background()})
+	}
+
+	w := &switchWriter{broken: 1}
+	sp, err := newSpool(cfg)
+	assert.NoError(t, err)
+	aop := newAsyncOutput(w, api.All, 10, 2, sp)
+	aop.SetFormatter(f)
+	var reported int32
+	aop.SetErrorHandler(func(error) { atomic.AddInt32(&reported, 1) })
+	send(aop, "1")
+	// the buffered events are on the disk before written
+	aop.Flush()
+	assert.Len(t, spoolFiles(dir), 1)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reported))
+	// the full batch is spooled by the loop, the replaying is not retried in the retry interval
+	send(aop, "2")
+	send(aop, "3")
+	aop.Close()
+	assert.Equal(t, api.OutputStats{}, aop.Stats())
+	assert.Len(t, spoolFiles(dir), 1)
+	assert.Equal(t, 3, sp.segs[0].events)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reported))
+
+	// the spooled events are replayed by the next run before the new events
+	atomic.StoreInt32(&w.broken, 0)
+	sp, err = newSpool(cfg)
+	assert.NoError(t, err)
+	aop = newAsyncOutput(w, api.All, 10, 10, sp)
+	aop.SetFormatter(f)
+	send(aop, "4")
+	aop.Flush()
+	assert.Equal(t, "1|2|3|4|", w.String())
+	assert.Equal(t, api.OutputStats{Events: 4, Bytes: 8}, aop.Stats())
+	aop.Close()
+	assert.Empty(t, spoolFiles(dir))
+
+	_, err = newSpool(api.CfgOutput{"type": "console", "name": "o1", "spool_dir": dir})
+	assert.Error(t, err)
+	sp, err = newSpool(api.CfgOutput{"type": "console", "name": "o1"})
+	assert.NoError(t, err)
+	assert.Nil(t, sp)
+}
+
+func TestGetSpoolSize(t *testing.T) {
+	for str, size := range map[string]int64{
+		"2K":  2 * 1024,
+		"3M":  3 * 1024 * 1024,
+		"1G":  1024 * 1024 * 1024,
+		"100": 100,
+		"":    defaultSpoolMaxSize,
+		"x":   defaultSpoolMaxSize,
+		"0M":  defaultSpoolMaxSize,
+	} {
+		assert.Equal(t, size, getSpoolSize(str, defaultSpoolMaxSize), str)
+	}
+}
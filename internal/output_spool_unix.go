@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// lockSpoolDir lock the directory by the lock file in it, the lock is released when the file closed
+func lockSpoolDir(dir string) (*os.File, error) {
+	f, err := os.OpenFile(filepath.Join(dir, spoolLockFile), os.O_RDWR|os.O_CREATE, defaultFilePermissions)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, fmt.Errorf("spool_dir %s is in use by another output", dir)
+		}
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// errorSharingViolation is ERROR_SHARING_VIOLATION, the file is opened by another process without sharing
+const errorSharingViolation syscall.Errno = 32
+
+// lockSpoolDir lock the directory by opening the lock file in it without sharing, the lock is released
+// when the file closed
+func lockSpoolDir(dir string) (*os.File, error) {
+	path := filepath.Join(dir, spoolLockFile)
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+		syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		if err == errorSharingViolation {
+			return nil, fmt.Errorf("spool_dir %s is in use by another output", dir)
+		}
+		return nil, err
+	}
+	return os.NewFile(uintptr(h), path), nil
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"log/syslog"
+	"sync/atomic"
 
 	"github.com/xtfly/log4g/api"
 )
@@ -11,14 +12,34 @@ const (
 )
 
 type syslogOutput struct {
-	w *syslog.Writer
-	f api.Formatter
-	t api.Level //threshold
+	w  *syslog.Writer
+	f  api.Formatter
+	t  api.Level    //threshold
+	fs []api.Filter // evaluated after the threshold
+	s  *sampler     // evaluated after the filters
+	h  func(err error)
+
+	events  uint64 // atomic
+	bytes   uint64 // atomic
+	dropped uint64 // atomic
+	errors  uint64 // atomic
 }
 
 func (o *syslogOutput) Send(e *api.Event) {
-	if e.Level < o.t {
-		return
+	_ = o.Write(e)
+}
+
+// Write a event to syslog and return the error of the syslog writer, the error is also reported to the handler
+func (o *syslogOutput) Write(e *api.Event) error {
+	_, err := o.writeEvent(e)
+	return err
+}
+
+// writeEvent write a event like Write and return whether it is written
+func (o *syslogOutput) writeEvent(e *api.Event) (written bool, err error) {
+	if !acceptEvent(e, o.t, o.fs, o.s) {
+		atomic.AddUint64(&o.dropped, 1)
+		return false, nil
 	}
 
 	m := ""
@@ -30,23 +51,55 @@ func (o *syslogOutput) Send(e *api.Event) {
 
 	switch e.Level {
 	case api.Trace, api.Debug:
-		o.w.Debug(m)
+		err = o.w.Debug(m)
 	case api.Info:
-		o.w.Info(m)
+		err = o.w.Info(m)
 	case api.Warn:
-		o.w.Warning(m)
+		err = o.w.Warning(m)
 	case api.Error:
-		o.w.Err(m)
+		err = o.w.Err(m)
 	case api.Critical:
-		o.w.Crit(m)
+		err = o.w.Crit(m)
+	}
+	if err != nil {
+		atomic.AddUint64(&o.errors, 1)
+		if o.h != nil {
+			o.h(markStatusError(err, isStatusRecord(e)))
+		} else {
+			reportInternalError(err)
+		}
+		return false, err
+	}
+	atomic.AddUint64(&o.events, 1)
+	atomic.AddUint64(&o.bytes, uint64(len(m)))
+	return true, nil
+}
+
+// Stats return the runtime statistics
+func (o *syslogOutput) Stats() api.OutputStats {
+	return api.OutputStats{
+		Events:  atomic.LoadUint64(&o.events),
+		Bytes:   atomic.LoadUint64(&o.bytes),
+		Dropped: atomic.LoadUint64(&o.dropped),
+		Errors:  atomic.LoadUint64(&o.errors),
 	}
 }
 
+// SetErrorHandler set the handler of the errors of writing, the errors are printed to stderr if not set
+func (o *syslogOutput) SetErrorHandler(h func(err error)) {
+	o.h = h
+}
+
 // SetFormatter ...
 func (o *syslogOutput) SetFormatter(f api.Formatter) {
 	o.f = f
 }
 
+// SetFilters set the filters evaluated after the threshold
+func (o *syslogOutput) SetFilters(fs []api.Filter) {
+	o.fs = fs
+}
+
 // CallerInfoFlag return the formater max caller flag index
 func (o *syslogOutput) CallerInfoFlag() int {
 	if o.f != nil {
@@ -55,8 +108,11 @@ func (o *syslogOutput) CallerInfoFlag() int {
 	return ciNoneFlog
 }
 
-// Close the syslog writer related to this output
+// Close the syslog writer related to this output, the last summary of the sampler is sent before
 func (o *syslogOutput) Close() {
+	if o.s != nil {
+		o.s.close()
+	}
 	if o.w != nil {
 		o.w.Close()
 	}
@@ -64,6 +120,10 @@ func (o *syslogOutput) Close() {
 
 // NewSyslogOutput return a output instance that output message to syslog
 func NewSyslogOutput(cfg api.CfgOutput) (api.Output, error) {
+	s, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
 	w, err := syslog.New(syslog.LOG_CRIT, cfg["prefix"])
 	if err != nil {
 		return nil, err
@@ -72,5 +132,9 @@ func NewSyslogOutput(cfg api.CfgOutput) (api.Output, error) {
 		w: w,
 		t: GetThresholdLvl(cfg["threshold"]),
 	}
+	if s != nil {
+		r.s = s
+		s.start(r.Send)
+	}
 	return r, nil
 }
@@ -0,0 +1,76 @@
+package log4g
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	// lineWriterMaxLen is the max length of a partial line, it is written as a line when exceeded
+	lineWriterMaxLen = 64 * 1024
+)
+
+// lineWriter splits the written bytes into lines and writes each line as a event
+type lineWriter struct {
+	sync.Mutex
+	logger api.Logger
+	lvl    api.Level
+	buf    bytes.Buffer // the partial line waiting for newline
+	closed bool
+}
+
+// NewLineWriter return a io.WriteCloser which writes each line to the Logger with the level,
+// the partial line is buffered until a newline is written or Close is called.
+// It can be used to capture the stdout and stderr of exec.Cmd.
+func NewLineWriter(logger api.Logger, lvl api.Level) io.WriteCloser {
+	return &lineWriter{logger: logger, lvl: lvl}
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.buf.Write(p)
+			if w.buf.Len() >= lineWriterMaxLen {
+				w.emit()
+			}
+			break
+		}
+		w.buf.Write(p[:i])
+		w.emit()
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Close writes the partial line, the following Write returns io.ErrClosedPipe
+func (w *lineWriter) Close() error {
+	w.Lock()
+	defer w.Unlock()
+	if w.closed {
+		return nil
+	}
+	if w.buf.Len() > 0 {
+		w.emit()
+	}
+	w.closed = true
+	return nil
+}
+
+// emit writes the buffered line as a event and reset the buffer
+func (w *lineWriter) emit() {
+	line := bytes.TrimSuffix(w.buf.Bytes(), []byte{'\r'})
+	w.logger.Printf(w.lvl, "", string(line))
+	w.buf.Reset()
+}
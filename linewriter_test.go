@@ -0,0 +1,37 @@
+package log4g
+
+import (
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestLineWriter(t *testing.T) {
+	ctx, op := newTestContext(t, "%{lvl}|%{msg}\n")
+	defer ctx.Close()
+
+	w := NewLineWriter(ctx.GetLogger("line"), api.Warn)
+	_, _ = w.Write([]byte("a\r\nb"))
+	_, _ = w.Write([]byte("c\n\nd"))
+	assert.Equal(t, "WRN|a\nWRN|bc\nWRN|\n", op.String())
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "WRN|a\nWRN|bc\nWRN|\nWRN|d\n", op.String())
+	_, err := w.Write([]byte("e"))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
+
+func TestLineWriterCmd(t *testing.T) {
+	ctx, op := newTestContext(t, "%{msg}\n")
+	defer ctx.Close()
+
+	w := NewLineWriter(ctx.GetLogger("cmd"), api.Info)
+	cmd := exec.Command("echo", "hello")
+	cmd.Stdout = w
+	assert.NoError(t, cmd.Run())
+	_ = w.Close()
+	assert.Equal(t, "hello\n", op.String())
+}
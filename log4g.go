@@ -16,3 +16,16 @@ func GetLogger(name string) api.Logger {
 func GetManager() api.Manager {
 	return internal.GetManager()
 }
+
+// NewLoggerContext return a self-contained logger context which has its own manager, configuration and outputs,
+// nil config means the default console config. The context is independent of the global one used by GetLogger.
+func NewLoggerContext(cfg *api.Config) (api.LoggerContext, error) {
+	return internal.NewLoggerContext(cfg)
+}
+
+// Init apply the config and replace the signal handling of the global manager by the options,
+// set the environment variable LOG4G_INIT=manual to skip the default initialization which applies
+// the config file of LOG4G_CONFIG or a console config, and closes the manager on SIGINT and SIGTERM.
+func Init(opts api.InitOptions) error {
+	return internal.Init(opts)
+}
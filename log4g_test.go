@@ -0,0 +1,48 @@
+package log4g
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+// bufOutput is a output writes formatted events to a buffer for testing
+type bufOutput struct {
+	sync.Mutex
+	buf bytes.Buffer
+	f   api.Formatter
+}
+
+func (o *bufOutput) Send(e *api.Event) {
+	o.Lock()
+	o.buf.Write(o.f.Format(e))
+	o.Unlock()
+}
+
+func (o *bufOutput) SetFormatter(f api.Formatter) { o.f = f }
+func (o *bufOutput) CallerInfoFlag() int          { return o.f.CallerInfoFlag() }
+func (o *bufOutput) Close()                       {}
+
+func (o *bufOutput) String() string {
+	o.Lock()
+	defer o.Unlock()
+	return o.buf.String()
+}
+
+// newTestContext return a logger context which root logger writes to a bufOutput by the layout
+func newTestContext(t *testing.T, layout string) (api.LoggerContext, *bufOutput) {
+	ctx, err := NewLoggerContext(nil)
+	assert.NoError(t, err)
+	op := &bufOutput{}
+	ctx.Manager().RegisterOutputCreator("buf", func(api.CfgOutput) (api.Output, error) { return op, nil })
+	err = ctx.Manager().SetConfig(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all", OutputNames: []string{"b1"}}},
+		Formats: []api.CfgFormat{{"type": "text", "name": "f1", "layout": layout}},
+		Outputs: []api.CfgOutput{{"type": "buf", "name": "b1", "format": "f1"}},
+	})
+	assert.NoError(t, err)
+	return ctx, op
+}
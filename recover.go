@@ -0,0 +1,61 @@
+package log4g
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/xtfly/log4g/api"
+	"github.com/xtfly/log4g/internal"
+)
+
+const (
+	recoverMaxDepth = 32
+)
+
+// RecoverAndLog recover the panic and write it with the Critical level, the panic value and the stack,
+// then flush the outputs of the manager which the writer belongs to, and panic again if repanic is true.
+// It must be called by defer directly:
+//
+//	defer log4g.RecoverAndLog(logger.WithFields(fields...), false)
+func RecoverAndLog(w api.Writer, repanic bool) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if frame, ok := panicCaller(); ok {
+		w = internal.WithCaller(w, frame)
+	}
+	w.Criticalf("panic: %v\n%s", v, debug.Stack())
+	internal.Flush(w)
+
+	if repanic {
+		panic(v)
+	}
+}
+
+// Go run fn in a new goroutine, the panic of fn is recovered and written by RecoverAndLog
+func Go(w api.Writer, fn func()) {
+	go func() {
+		defer RecoverAndLog(w, false)
+		fn()
+	}()
+}
+
+// panicCaller return the frame which panics
+func panicCaller() (runtime.Frame, bool) {
+	pcs := make([]uintptr, recoverMaxDepth)
+	// skip runtime.Callers, panicCaller and RecoverAndLog
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return frame, frame.PC != 0
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
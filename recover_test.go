@@ -0,0 +1,40 @@
+package log4g
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func panicFunc() {
+	var m map[string]int
+	m["a"] = 1
+}
+
+func TestRecoverAndLog(t *testing.T) {
+	ctx, op := newTestContext(t, "%{shortfunc}|%{lvl}|%{k}|%{msg}\n")
+	defer ctx.Close()
+	w := ctx.GetLogger("recover").WithFields(api.Field{Key: "k", Value: "v"})
+
+	func() {
+		defer RecoverAndLog(w, false)
+		panicFunc()
+	}()
+	assert.Regexp(t, `^panicFunc\|CRI\|v\|panic: assignment to entry in nil map\n(.|\n)*recover_test.go`, op.String())
+
+	assert.Panics(t, func() {
+		defer RecoverAndLog(w, true)
+		panic("again")
+	})
+
+	Go(w, func() {
+		panic("goroutine")
+	})
+	for i := 0; i < 100 && !strings.Contains(op.String(), "panic: goroutine"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Contains(t, op.String(), "panic: goroutine")
+}
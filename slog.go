@@ -0,0 +1,107 @@
+//go:build go1.21
+
+package log4g
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/xtfly/log4g/api"
+)
+
+// slogHandler implements slog.Handler, the records are written to a Logger
+type slogHandler struct {
+	logger api.Logger
+	fields []api.Field // fields accumulated by WithAttrs
+	group  string      // key prefix accumulated by WithGroup, like 'g1.g2.'
+}
+
+// NewSlogHandler return a slog.Handler which writes records to the Logger,
+// the attributes are written as fields with the keys prefixed by the group names.
+func NewSlogHandler(logger api.Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+// Enabled reports whether the Logger enables the level
+func (h *slogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return h.logger.LevelEnabled(slogLevel(l))
+}
+
+// Handle writes the record to the Logger with the caller of the record
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]api.Field, len(h.fields), len(h.fields)+r.NumAttrs())
+	copy(fields, h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.group, a)
+		return true
+	})
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = api.WithFields(ctx, fields...)
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ctx = api.WithCaller(ctx, frame)
+	}
+	h.logger.WithCtx(ctx).Printf(slogLevel(r.Level), "", r.Message)
+	return nil
+}
+
+// WithAttrs return a new handler with the attributes accumulated
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]api.Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(fields, h.fields)
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.group, a)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, group: h.group}
+}
+
+// WithGroup return a new handler which prefixes the keys of the following attributes by the name
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, fields: h.fields, group: h.group + name + "."}
+}
+
+// appendSlogAttr append the attribute as fields, the group attribute is flattened
+func appendSlogAttr(fields []api.Field, prefix string, a slog.Attr) []api.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendSlogAttr(fields, prefix, ga)
+		}
+		return fields
+	}
+	return append(fields, api.Field{Key: prefix + a.Key, Value: a.Value.Any()})
+}
+
+// slogLevel return the level mapped from the slog level
+func slogLevel(l slog.Level) api.Level {
+	switch {
+	case l < slog.LevelDebug:
+		return api.Trace
+	case l < slog.LevelInfo:
+		return api.Debug
+	case l < slog.LevelWarn:
+		return api.Info
+	case l < slog.LevelError:
+		return api.Warn
+	case l < slog.LevelError+4:
+		return api.Error
+	}
+	return api.Critical
+}
@@ -0,0 +1,26 @@
+//go:build go1.21
+
+package log4g
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestSlogHandler(t *testing.T) {
+	ctx, op := newTestContext(t, "%{shortfunc}|%{lvl}|%{a}|%{g.b}|%{g.h.c}|%{msg}\n")
+	defer ctx.Close()
+
+	log := ctx.GetLogger("slog")
+	log.SetLevel(api.Info)
+	sl := slog.New(NewSlogHandler(log)).With("a", 1)
+	sl.Debug("debug")
+	sl.WithGroup("g").Warn("warn", "b", "x", slog.Group("h", "c", true))
+	sl.Log(context.Background(), slog.LevelError+4, "critical")
+
+	assert.Equal(t, "TestSlogHandler|WRN|1|x|true|warn\nTestSlogHandler|CRI|1|<nil>|<nil>|critical\n", op.String())
+}
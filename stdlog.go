@@ -0,0 +1,116 @@
+//go:build go1.14
+// +build go1.14
+
+package log4g
+
+import (
+	"context"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/xtfly/log4g/api"
+)
+
+const (
+	stdLogDateLen  = len("2006/01/02 ")
+	stdLogTimeLen  = len("15:04:05 ")
+	stdLogMicroLen = len(".000000")
+	stdLogMaxDepth = 32
+)
+
+// stdLogWriter is the io.Writer of a standard logger, it strips the prefix, date, time and file
+// written by the standard logger, and writes the message to a Logger with the real call site.
+type stdLogWriter struct {
+	logger api.Logger
+	lvl    api.Level
+	prefix string
+	flags  int
+}
+
+// NewStdLogger return a standard logger which writes to the Logger with the level,
+// it can be used by the APIs like http.Server.ErrorLog.
+func NewStdLogger(logger api.Logger, lvl api.Level) *log.Logger {
+	return log.New(&stdLogWriter{logger: logger, lvl: lvl}, "", 0)
+}
+
+// RedirectStdLog redirect the output of the standard log package to the Logger with the level,
+// the prefix and flags of the standard log at the time of calling are used to parse the output,
+// the returned function restores the output, prefix and flags of the standard log at the time of calling.
+func RedirectStdLog(logger api.Logger, lvl api.Level) (restore func()) {
+	out, prefix, flags := log.Writer(), log.Prefix(), log.Flags()
+	log.SetOutput(&stdLogWriter{logger: logger, lvl: lvl, prefix: prefix, flags: flags})
+	return func() {
+		log.SetOutput(out)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+	}
+}
+
+// Write implements io.Writer, p is a line written by a standard logger
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	if !w.logger.LevelEnabled(w.lvl) {
+		return len(p), nil
+	}
+
+	ctx := context.Background()
+	if w.prefix != "" {
+		ctx = api.WithFields(ctx, api.Field{Key: "prefix", Value: strings.TrimSpace(w.prefix)})
+	}
+	if frame, ok := stdLogCaller(); ok {
+		ctx = api.WithCaller(ctx, frame)
+	}
+	w.logger.WithCtx(ctx).Printf(w.lvl, "", w.strip(string(p)))
+	return len(p), nil
+}
+
+// strip return the message by removing the prefix, date, time and file written by the standard logger
+func (w *stdLogWriter) strip(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	if w.flags&log.Lmsgprefix == 0 {
+		s = strings.TrimPrefix(s, w.prefix)
+	}
+	if w.flags&log.Ldate != 0 {
+		s = stdLogCut(s, stdLogDateLen)
+	}
+	if w.flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n := stdLogTimeLen
+		if w.flags&log.Lmicroseconds != 0 {
+			n += stdLogMicroLen
+		}
+		s = stdLogCut(s, n)
+	}
+	if w.flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(s, ": "); i >= 0 {
+			s = s[i+2:]
+		}
+	}
+	if w.flags&log.Lmsgprefix != 0 {
+		s = strings.TrimPrefix(s, w.prefix)
+	}
+	return s
+}
+
+func stdLogCut(s string, n int) string {
+	if len(s) < n {
+		return ""
+	}
+	return s[n:]
+}
+
+// stdLogCaller return the frame which calls the standard log package
+func stdLogCaller() (runtime.Frame, bool) {
+	pcs := make([]uintptr, stdLogMaxDepth)
+	// skip runtime.Callers, stdLogCaller and stdLogWriter.Write
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "log.") {
+			return frame, frame.PC != 0
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
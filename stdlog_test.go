@@ -0,0 +1,78 @@
+//go:build go1.14
+// +build go1.14
+
+package log4g
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xtfly/log4g/api"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	ctx, op := newTestContext(t, "%{shortfile}|%{shortfunc}|%{lvl}|%{msg}\n")
+	defer ctx.Close()
+
+	std := NewStdLogger(ctx.GetLogger("std"), api.Warn)
+	std.Printf("hello %d", 1)
+	assert.Equal(t, "stdlog_test.go|TestNewStdLogger|WRN|hello 1\n", op.String())
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	ctx, op := newTestContext(t, "%{shortfile}|%{prefix}|%{msg}\n")
+	defer ctx.Close()
+
+	out, prefix, flags := log.Writer(), log.Prefix(), log.Flags()
+	defer func() {
+		log.SetOutput(out)
+		log.SetPrefix(prefix)
+		log.SetFlags(flags)
+	}()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetPrefix("[std] ")
+	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+
+	restore := RedirectStdLog(ctx.GetLogger("std"), api.Info)
+	log.Println("hello")
+	log.SetPrefix("")
+	log.SetFlags(0)
+	restore()
+	assert.Equal(t, "stdlog_test.go|[std]|hello\n", op.String())
+
+	// the output, prefix and flags at the time of redirecting are restored
+	assert.Equal(t, &buf, log.Writer())
+	assert.Equal(t, "[std] ", log.Prefix())
+	assert.Equal(t, log.LstdFlags|log.Lmicroseconds|log.Lshortfile, log.Flags())
+	assert.Empty(t, buf.String())
+
+	w := &stdLogWriter{prefix: "p:", flags: log.Ldate | log.Lmsgprefix}
+	assert.Equal(t, "msg", w.strip("2009/01/23 p:msg\n"))
+}
+
+func TestRedirectStdLogNoOutputs(t *testing.T) {
+	ctx, err := NewLoggerContext(&api.Config{
+		Loggers: []api.CfgLogger{{Name: "root", Level: "all"}},
+	})
+	assert.NoError(t, err)
+	defer ctx.Close()
+
+	// the warning of no outputs is not written back to the logger by the standard log
+	restore := RedirectStdLog(ctx.GetLogger("std"), api.Info)
+	done := make(chan struct{})
+	go func() {
+		log.Println("hello")
+		close(done)
+	}()
+	select {
+	case <-done:
+		restore()
+	case <-time.After(5 * time.Second):
+		// the standard log is locked by the blocked goroutine, it can not be restored
+		t.Fatal("logging without outputs does not return")
+	}
+}